@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// Permissions klog needs to find pods and read their logs
+var requiredAccess = []authorizationv1.ResourceAttributes{
+	{Verb: "list", Resource: "pods"},
+	{Verb: "get", Resource: "pods"},
+	{Verb: "get", Resource: "pods", Subresource: "log"},
+}
+
+// Permission dump needs to save the events of the pods
+var eventsAccess = authorizationv1.ResourceAttributes{Verb: "list", Resource: "events"}
+
+var checkCmd = &cobra.Command{
+	Use:     "check [namespace]...",
+	Short:   "Check the permissions needed to read pod logs, in all namespaces by default.",
+	Example: "  klog check shop payments",
+	Run: func(cmd *cobra.Command, args []string) {
+		namespaces := args
+		if len(namespaces) == 0 {
+			namespaces = []string{""}
+		}
+
+		if err := checkPermissions(context.Background(), newClientset(flags), namespaces, requiredAccess...); err != nil {
+			pterm.Error.Printf("Error checking permissions: %v\n", err)
+			os.Exit(1)
+		}
+		pterm.Success.Println("Permissions to read pod logs granted")
+	},
+}
+
+// Function to verify with SelfSubjectAccessReviews that the user has the permissions in the namespaces
+func checkPermissions(ctx context.Context, clientset *kubernetes.Clientset, namespaces []string, accesses ...authorizationv1.ResourceAttributes) error {
+	var missing []string
+
+	for _, namespace := range namespaces {
+		var denied []string
+		for _, access := range accesses {
+			access.Namespace = namespace
+			review := &authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &access},
+			}
+
+			result, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+			if err != nil {
+				// The pre-check is best effort, the API server will still enforce access
+				pterm.Warning.Printf("Unable to verify permissions: %v\n", err)
+				return nil
+			}
+			if !result.Status.Allowed {
+				denied = append(denied, accessString(access))
+			}
+		}
+
+		if len(denied) > 0 {
+			scope := namespace
+			if scope == "" {
+				scope = "all namespaces"
+			}
+			missing = append(missing, fmt.Sprintf("%s: %s", scope, strings.Join(denied, ", ")))
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing permissions\n  %s", strings.Join(missing, "\n  "))
+	}
+	return nil
+}
+
+func accessString(access authorizationv1.ResourceAttributes) string {
+	resource := access.Resource
+	if access.Subresource != "" {
+		resource += "/" + access.Subresource
+	}
+	return access.Verb + " " + resource
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/pterm/pterm"
+)
+
+// Serialize the output of concurrent streams
+var outputMu sync.Mutex
+
+// Function to choose the container to stream in a pod without prompting
+func podContainer(pod v1.Pod, opts Options) string {
+	if opts.Container != "" {
+		// Pods without a matching container fail with the name given to -c
+		if matched := matchContainers(pod, opts); len(matched) > 0 {
+			return matched[0].Name
+		}
+		return opts.Container
+	}
+	if name, ok := pod.Annotations["kubectl.kubernetes.io/default-container"]; ok && !excludedContainer(name) {
+		return name
+	}
+	for _, container := range pod.Spec.Containers {
+		if !excludedContainer(container.Name) {
+			return container.Name
+		}
+	}
+	return pod.Spec.Containers[0].Name
+}
+
+// Function to tell whether a container runs to completion before the main containers start,
+// unlike the sidecars declared as init containers that keep running
+func runsBeforeMain(pod v1.Pod, container string) bool {
+	for _, init := range pod.Spec.InitContainers {
+		if init.Name == container {
+			return init.RestartPolicy == nil || *init.RestartPolicy != v1.ContainerRestartPolicyAlways
+		}
+	}
+	return false
+}
+
+// Function to get the sources streamed for a pod: one container, or each of them with --all-containers
+func podSources(pod v1.Pod, opts Options) []logSource {
+	rememberPodMetadata(pod)
+	names := []string{podContainer(pod, opts)}
+	if opts.AllContainers {
+		names = nil
+		candidates := selectableContainers(pod, opts)
+		if opts.Container != "" {
+			candidates = matchContainers(pod, opts)
+		}
+		for _, container := range candidates {
+			names = append(names, container.Name)
+		}
+	}
+
+	sources := make([]logSource, len(names))
+	for i, container := range names {
+		sources[i] = logSource{Namespace: pod.Namespace, Pod: pod.Name, Container: container, Workload: workloadName(pod), Node: pod.Spec.NodeName}
+	}
+	return sources
+}
+
+// Function to stream the logs of every matched pod concurrently, and with -a of the pods matching
+// the pattern created afterwards
+func streamAllPods(ctx context.Context, clientset *kubernetes.Clientset, pods []v1.Pod, pattern string, opts Options) {
+	duplicates.addPods(len(pods))
+
+	perPod := make([][]logSource, len(pods))
+	var sources []logSource
+	var budgetPods []v1.Pod
+	var containers []string
+	for i, pod := range pods {
+		perPod[i] = podSources(pod, opts)
+		for _, src := range perPod[i] {
+			sources = append(sources, src)
+			budgetPods = append(budgetPods, pod)
+			containers = append(containers, src.Container)
+		}
+	}
+	// One kubectl command per stream, kubectl following a single pod
+	if opts.PrintKubectl {
+		for _, src := range sources {
+			fmt.Println(kubectlCommand(src.Namespace, src.Pod, src.Container, opts))
+		}
+		return
+	}
+	checkFetchBudget(ctx, clientset, budgetPods, containers, false, opts)
+	assignSessionColors(sources, opts.Deterministic)
+	startSession(ctx, clientset, sources, opts)
+
+	var wg sync.WaitGroup
+	for i, pod := range pods {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			streamPod(ctx, clientset, pod, perPod[i], opts)
+		}()
+	}
+
+	// Function to stream a pod joining the session
+	attach := func(pod v1.Pod) {
+		sources := podSources(pod, opts)
+
+		outputMu.Lock()
+		sessionSources = append(sessionSources, sources...)
+		outputMu.Unlock()
+		duplicates.addPods(1)
+		attachSessionColors(sources)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			streamPod(ctx, clientset, pod, sources, opts)
+		}()
+	}
+	controlAttach = func(pattern string) (int, error) {
+		return attachMatchingPods(ctx, clientset, pattern, opts, attach)
+	}
+
+	if opts.AllPods {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			watchNewPods(ctx, clientset, pods, pattern, opts, attach)
+		}()
+	}
+	wg.Wait()
+}
+
+// Function to stream the containers of a pod, init containers one after the other then the main containers together
+func streamPod(ctx context.Context, clientset *kubernetes.Clientset, pod v1.Pod, sources []logSource, opts Options) {
+	var main []logSource
+	for _, src := range sources {
+		if runsBeforeMain(pod, src.Container) {
+			streamContainer(ctx, clientset, pod, src, opts)
+		} else {
+			main = append(main, src)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, src := range main {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			streamContainer(ctx, clientset, pod, src, opts)
+		}()
+	}
+	wg.Wait()
+}
+
+// Function to wait for a container of one of the concurrent streams and stream its logs
+func streamContainer(ctx context.Context, clientset *kubernetes.Clientset, pod v1.Pod, src logSource, opts Options) {
+	pterm.Info.Printf("Displaying logs for container '%s' in pod '%s'\n", src.Container, src.Pod)
+
+	if !opts.LastContainer {
+		// Spinners of concurrent pods would overwrite each other
+		started, err := waitForContainer(ctx, clientset, &pod, src.Container, startPlainProgress)
+		if err != nil {
+			pterm.Error.Printf("Error waiting for container of pod '%s': %v\n", src.Pod, err)
+			return
+		}
+		pod = *started
+	}
+	if err := streamLogs(ctx, clientset, src, buildLogOptions(&pod, src.Container, opts), opts); err != nil {
+		pterm.Error.Printf("Error streaming logs of pod '%s': %v\n", src.Pod, err)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// Number of message templates shown in the analysis
+const analyzeRows = 20
+
+var analyzeCmd = &cobra.Command{
+	Use:     "analyze <pod-name>",
+	Short:   "Summarize the levels and most frequent messages in the logs of a pod, or of every matched pod with -a.",
+	Example: "  klog analyze my-api -a -s 1",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		analyze(args[0], flags)
+	},
+}
+
+// Occurrences of a message template across the pods
+type templateCount struct {
+	template string
+	level    string
+	count    int
+	pods     map[string]struct{}
+}
+
+// Function to print the level counts of each pod and the most frequent message templates
+func analyze(pattern string, opts Options) {
+	ctx := context.Background()
+	clientset := newClientset(opts)
+	pods, containers := selectedPods(ctx, clientset, pattern, opts)
+	checkFetchBudget(ctx, clientset, pods, containers, true, opts)
+
+	levelTable := pterm.TableData{{"Pod", "Container", "Lines", "Error", "Warning", "Debug"}}
+	templates := make(map[string]*templateCount)
+
+	for i, pod := range pods {
+		podLogOptions := buildLogOptions(&pods[i], containers[i], opts)
+		podLogOptions.Follow = false
+		podLogOptions.Timestamps = false
+
+		stream, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, podLogOptions).Stream(ctx)
+		if err != nil {
+			pterm.Error.Printf("Error fetching logs of pod '%s': %v\n", pod.Name, err)
+			os.Exit(1)
+		}
+
+		lines := 0
+		levels := make(map[string]int)
+		scanner := newLineScanner(stream)
+		for scanner.Scan() {
+			level, _ := detectLevel(scanner.Text())
+			lines++
+			levels[level]++
+
+			template := normalizeMessage(scanner.Text())
+			count, ok := templates[template]
+			if !ok {
+				count = &templateCount{template: template, level: level, pods: make(map[string]struct{})}
+				templates[template] = count
+			}
+			count.count++
+			count.pods[pod.Name] = struct{}{}
+		}
+		stream.Close()
+		if err := scanner.Err(); err != nil {
+			pterm.Error.Printf("Error reading logs of pod '%s': %v\n", pod.Name, err)
+			os.Exit(1)
+		}
+
+		levelTable = append(levelTable, []string{pod.Name, containers[i], fmt.Sprint(lines),
+			fmt.Sprint(levels["error"]), fmt.Sprint(levels["warning"] + levels["panic"]), fmt.Sprint(levels["debug"])})
+	}
+
+	sorted := make([]*templateCount, 0, len(templates))
+	for _, count := range templates {
+		sorted = append(sorted, count)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].count != sorted[j].count {
+			return sorted[i].count > sorted[j].count
+		}
+		return sorted[i].template < sorted[j].template
+	})
+	if len(sorted) > analyzeRows {
+		sorted = sorted[:analyzeRows]
+	}
+
+	templateTable := pterm.TableData{{"Count", "Pods", "Template"}}
+	for _, count := range sorted {
+		templateTable = append(templateTable, []string{fmt.Sprint(count.count), fmt.Sprint(len(count.pods)), levelColor(count.level)(count.template)})
+	}
+
+	_ = pterm.DefaultTable.WithHasHeader().WithData(levelTable).Render()
+	fmt.Println()
+	_ = pterm.DefaultTable.WithHasHeader().WithData(templateTable).Render()
+}
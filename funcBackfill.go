@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/pterm/pterm"
+)
+
+// Delay between the requested start and the first kubelet line tolerated before backfilling
+const backfillTolerance = time.Minute
+
+// Function to get the time of the first line the kubelet still has since the requested start
+func firstLogTime(ctx context.Context, clientset *kubernetes.Clientset, src logSource, podLogOptions *v1.PodLogOptions) (time.Time, error) {
+	limitBytes := int64(4096)
+	probe := &v1.PodLogOptions{
+		Container:  podLogOptions.Container,
+		Previous:   podLogOptions.Previous,
+		SinceTime:  logsSince(podLogOptions),
+		Timestamps: true,
+		LimitBytes: &limitBytes,
+	}
+
+	data, err := clientset.CoreV1().Pods(src.Namespace).GetLogs(src.Pod, probe).DoRaw(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	timestamp, _, _ := strings.Cut(string(data), " ")
+	if timestamp == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, timestamp)
+}
+
+// Function to print from Loki the lines the kubelet already rotated away since the requested start
+func backfillFromLoki(ctx context.Context, clientset *kubernetes.Clientset, src logSource, podLogOptions *v1.PodLogOptions, opts Options) {
+	sinceTime := logsSince(podLogOptions)
+	if opts.LokiURL == "" || sinceTime == nil {
+		return
+	}
+
+	since := sinceTime.Time
+	first, err := firstLogTime(ctx, clientset, src, podLogOptions)
+	if err != nil || first.IsZero() || first.Sub(since) < backfillTolerance {
+		return
+	}
+
+	pterm.Info.Printf("Pod '%s' logs start at %s, backfilling from Loki since %s\n",
+		src.Pod, first.Format(timestampFormat), since.Format(timestampFormat))
+
+	query := fmt.Sprintf(`{namespace=%q, pod=%q, container=%q}`, src.Namespace, src.Pod, src.Container)
+	if err := newLokiClient(opts.LokiURL).printRange(ctx, query, since, first, opts); err != nil {
+		pterm.Warning.Printf("Error backfilling from Loki: %v\n", err)
+	}
+}
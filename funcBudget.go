@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/pterm/pterm"
+)
+
+// Bytes read from each container to estimate its log rate
+const budgetProbeBytes = 64 * 1024
+
+// Containers probed at once, the API server throttling klog beyond
+const budgetProbeConcurrency = 8
+
+// Function to estimate the bytes the kubelet will send for a container from a sample of its logs
+func estimateFetch(ctx context.Context, clientset *kubernetes.Clientset, pod *v1.Pod, container string, opts Options) (int64, error) {
+	limitBytes := int64(budgetProbeBytes)
+	podLogOptions := buildLogOptions(pod, container, opts)
+	podLogOptions.Follow = false
+	podLogOptions.Timestamps = true
+	podLogOptions.LimitBytes = &limitBytes
+
+	data, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, podLogOptions).DoRaw(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// The whole range fits in the sample
+	if len(data) < budgetProbeBytes {
+		return int64(len(data)), nil
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	first, errFirst := time.Parse(time.RFC3339Nano, strings.SplitN(lines[0], " ", 2)[0])
+	last, errLast := time.Parse(time.RFC3339Nano, strings.SplitN(lines[len(lines)-1], " ", 2)[0])
+	if errFirst != nil || errLast != nil || !last.After(first) {
+		return int64(len(data)), nil
+	}
+
+	// Extrapolate the rate of the sample to the rest of the range
+	rate := float64(len(data)) / last.Sub(first).Seconds()
+	return int64(rate * time.Since(first).Seconds()), nil
+}
+
+// Function to ask for confirmation before pulling more than --fetch-budget through the API server,
+// when a --since, --since-time or --previous range is fetched, or the whole log with wholeLog
+func checkFetchBudget(ctx context.Context, clientset *kubernetes.Clientset, pods []v1.Pod, containers []string, wholeLog bool, opts Options) {
+	if opts.TailLines > 0 || opts.Yes {
+		return
+	}
+	if !wholeLog && opts.Since == 0 && opts.SinceTimestamp == "" && !opts.LastContainer {
+		return
+	}
+
+	var (
+		total int64
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		slots = make(chan struct{}, budgetProbeConcurrency)
+	)
+	for i := range pods {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			estimate, err := estimateFetch(ctx, clientset, &pods[i], containers[i], opts)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			total += estimate
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	budget := int64(opts.FetchBudget) * 1024 * 1024
+	if total <= budget {
+		return
+	}
+
+	if opts.NonInteractive {
+		pterm.Error.Printf("About %s of logs would be fetched from %d containers (budget %d MiB), use --tail, a shorter --since or --yes\n", humanBytes(total), len(pods), opts.FetchBudget)
+		os.Exit(1)
+	}
+
+	pterm.Warning.Printf("About %s of logs will be fetched from %d containers (budget %d MiB)\n", humanBytes(total), len(pods), opts.FetchBudget)
+	confirmed, _ := pterm.DefaultInteractiveConfirm.WithDefaultText("Fetch them anyway?").Show()
+	if !confirmed {
+		pterm.Info.Println("Use --tail or a shorter --since to reduce the volume, or --yes to skip this check")
+		os.Exit(0)
+	}
+}
+
+func humanBytes(bytes int64) string {
+	return pterm.Sprintf("%.1f MiB", float64(bytes)/1024/1024)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// How long a cached pod list stays valid
+const podCacheTTL = 30 * time.Second
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// Function to get the cache file of the pod list for a kubeconfig context, namespace and selectors
+func podCachePath(kubeContext string, namespace string, listOptions metav1.ListOptions) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	name := unsafeFileChars.ReplaceAllString(kubeContext, "_")
+	if namespace != "" || listOptions.LabelSelector != "" || listOptions.FieldSelector != "" {
+		hash := fnv.New32a()
+		_, _ = hash.Write([]byte(namespace + "\x00" + listOptions.LabelSelector + "\x00" + listOptions.FieldSelector))
+		name += fmt.Sprintf("-%08x", hash.Sum32())
+	}
+	return filepath.Join(cacheDir, "klog", "pods-"+name+".json"), nil
+}
+
+// Function to list pods of a namespace (all when empty), reusing a recent listing of the same context when available
+func listPods(ctx context.Context, clientset *kubernetes.Clientset, kubeContext string, namespace string, listOptions metav1.ListOptions, noCache bool) (*v1.PodList, error) {
+	cachePath, cacheErr := podCachePath(kubeContext, namespace, listOptions)
+
+	if !noCache && cacheErr == nil {
+		if info, err := os.Stat(cachePath); err == nil && time.Since(info.ModTime()) < podCacheTTL {
+			if data, err := os.ReadFile(cachePath); err == nil {
+				var cached v1.PodList
+				if err := json.Unmarshal(data, &cached); err == nil {
+					return &cached, nil
+				}
+			}
+		}
+	}
+
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, listOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Failing to write the cache never prevents streaming
+	if cacheErr == nil {
+		if data, err := json.Marshal(pods); err == nil {
+			if err := os.MkdirAll(filepath.Dir(cachePath), 0o700); err == nil {
+				_ = os.WriteFile(cachePath, data, 0o600)
+			}
+		}
+	}
+	return pods, nil
+}
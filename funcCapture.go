@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/pterm/pterm"
+)
+
+// Keep the recent lines of all streams and save them around trigger matches
+type captureBuffer struct {
+	mu        sync.Mutex
+	ctx       context.Context
+	clientset *kubernetes.Clientset
+	sources   []logSource
+	trigger   *regexp.Regexp
+	size      int
+	recent    []string
+	current   *capture
+	saving    sync.WaitGroup
+}
+
+// Evidence collected for one trigger match
+type capture struct {
+	dir       string
+	lines     []string
+	remaining int
+}
+
+var captures *captureBuffer
+
+// Function to enable trigger captures for the streamed sources
+func startCapture(ctx context.Context, clientset *kubernetes.Clientset, sources []logSource, opts Options) {
+	if opts.Trigger == "" {
+		return
+	}
+
+	trigger, err := regexp.Compile(opts.Trigger)
+	if err != nil {
+		pterm.Error.Printf("Invalid trigger pattern: %v\n", err)
+		os.Exit(1)
+	}
+
+	captures = &captureBuffer{
+		ctx:       ctx,
+		clientset: clientset,
+		sources:   sources,
+		trigger:   trigger,
+		size:      opts.Capture,
+	}
+	// A pod dying after the trigger, e.g. OOM killed, sends no more lines to complete its capture
+	onSessionEnd(captures.flush)
+}
+
+// Function to record a line and start or complete a capture
+func (c *captureBuffer) add(src logSource, line string) {
+	entry := fmt.Sprintf("[%s/%s] %s", src.Pod, src.Container, line)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.current != nil {
+		c.current.lines = append(c.current.lines, entry)
+		c.current.remaining--
+		if c.current.remaining <= 0 {
+			done := c.current
+			c.current = nil
+			c.saving.Add(1)
+			go func() {
+				defer c.saving.Done()
+				done.save()
+			}()
+		}
+	} else if c.trigger.MatchString(line) {
+		// Triggers of the same second get their own directory
+		if dir, err := os.MkdirTemp(".", fmt.Sprintf("capture-%s-", time.Now().Format("20060102-150405"))); err != nil {
+			pterm.Error.Printf("Error creating capture directory: %v\n", err)
+		} else {
+			c.current = &capture{
+				dir:       dir,
+				lines:     append(append([]string{}, c.recent...), entry),
+				remaining: c.size,
+			}
+			pterm.Warning.Printf("Trigger matched in pod '%s', capturing to %s\n", src.Pod, dir)
+			c.saving.Add(1)
+			go c.snapshotPods(dir)
+		}
+	}
+
+	c.recent = append(c.recent, entry)
+	if len(c.recent) > c.size {
+		c.recent = c.recent[len(c.recent)-c.size:]
+	}
+}
+
+// Function to save the capture in progress with the lines received so far, once the session ends
+func (c *captureBuffer) flush() {
+	c.mu.Lock()
+	pending := c.current
+	c.current = nil
+	c.mu.Unlock()
+	if pending != nil {
+		pending.save()
+	}
+	c.saving.Wait()
+}
+
+// Function to save the status of every streamed pod at trigger time
+func (c *captureBuffer) snapshotPods(dir string) {
+	defer c.saving.Done()
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		pterm.Error.Printf("Error creating capture directory: %v\n", err)
+		return
+	}
+
+	for _, src := range c.sources {
+		pod, err := c.clientset.CoreV1().Pods(src.Namespace).Get(c.ctx, src.Pod, metav1.GetOptions{})
+		if err != nil {
+			pterm.Error.Printf("Error fetching pod information: %v\n", err)
+			continue
+		}
+		if err := writePodManifest(pod, filepath.Join(dir, pod.Namespace+"_"+pod.Name+".yaml")); err != nil {
+			pterm.Error.Printf("Error saving pod status: %v\n", err)
+		}
+	}
+}
+
+func (c *capture) save() {
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		pterm.Error.Printf("Error creating capture directory: %v\n", err)
+		return
+	}
+
+	path := filepath.Join(c.dir, "lines.log")
+	if err := os.WriteFile(path, []byte(strings.Join(c.lines, "\n")+"\n"), 0o644); err != nil {
+		pterm.Error.Printf("Error saving capture: %v\n", err)
+		return
+	}
+	pterm.Success.Printf("Capture saved to %s\n", path)
+}
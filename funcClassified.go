@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// CSV file of the classified lines written with --emit-classified
+type classifiedWriter struct {
+	mu     sync.Mutex
+	file   *os.File
+	writer *csv.Writer
+}
+
+var classified *classifiedWriter
+
+// Function to create the --emit-classified file, a row per received line with its level and message template
+func startClassified(opts Options) {
+	if opts.EmitClassified == "" {
+		return
+	}
+
+	file, err := os.Create(opts.EmitClassified)
+	if err != nil {
+		pterm.Error.Printf("Error creating classified lines file: %v\n", err)
+		os.Exit(1)
+	}
+	classified = &classifiedWriter{file: file, writer: csv.NewWriter(file)}
+	_ = classified.writer.Write([]string{"timestamp", "pod", "container", "level", "template_hash", "message"})
+	onSessionEnd(closeClassified)
+
+	// Readable up to the last flush if klog is killed
+	go func() {
+		for range time.Tick(outputFlushInterval) {
+			classified.mu.Lock()
+			if classified.file != nil {
+				classified.writer.Flush()
+			}
+			classified.mu.Unlock()
+		}
+	}()
+}
+
+// Function to add a classified line, muted and filtered lines included
+func (c *classifiedWriter) add(src logSource, t time.Time, level string, line string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return
+	}
+	if err := c.writer.Write([]string{t.Format(time.RFC3339Nano), src.Pod, src.Container, level, fmt.Sprintf("%016x", fingerprint(line)), line}); err != nil {
+		pterm.Warning.Printf("Error writing classified lines: %v\n", err)
+	}
+}
+
+// Function to complete the --emit-classified file once the streams ended
+func closeClassified() {
+	if classified == nil {
+		return
+	}
+	classified.mu.Lock()
+	defer classified.mu.Unlock()
+	if classified.file == nil {
+		return
+	}
+	classified.writer.Flush()
+	_ = classified.file.Close()
+	classified.file = nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/pterm/pterm"
+)
+
+// Colors used to prefix the lines of each pod, red is kept for errors
+var podPalette = []pterm.Color{
+	pterm.FgGreen, pterm.FgBlue, pterm.FgMagenta, pterm.FgCyan, pterm.FgYellow,
+	pterm.FgLightGreen, pterm.FgLightBlue, pterm.FgLightMagenta, pterm.FgLightCyan, pterm.FgLightYellow,
+}
+
+// Extra 256-color codes used once the palette is exhausted, reds are excluded
+var extendedPalette = []ansi256{
+	33, 39, 45, 51, 69, 75, 81, 99, 105, 111, 117, 141, 147, 153, 171,
+	177, 183, 190, 192, 214, 220, 226, 228, 118, 120, 122, 36, 42, 48, 72,
+}
+
+// Color of the 256-color terminal space
+type ansi256 uint8
+
+func (c ansi256) Sprintf(format string, a ...interface{}) string {
+	if !pterm.PrintColor {
+		return fmt.Sprintf(format, a...)
+	}
+	return fmt.Sprintf("\x1b[38;5;%dm%s\x1b[0m", uint8(c), fmt.Sprintf(format, a...))
+}
+
+type colorizer interface {
+	Sprintf(format string, a ...interface{}) string
+}
+
+// Colors given in order to the pods of the session so that they don't collide
+var (
+	sessionColorsMu sync.RWMutex
+	sessionColors   = map[string]colorizer{}
+	// Set when the pods outnumber the palettes, every pod then being colored from its name
+	sessionColorsHashed bool
+)
+
+// Function to give each pod a distinct color when they fit in the palettes
+func assignSessionColors(sources []logSource, deterministic bool) {
+	sessionColorsMu.Lock()
+	defer sessionColorsMu.Unlock()
+
+	if len(sources) > len(podPalette)+len(extendedPalette) {
+		sessionColorsHashed = true
+		return
+	}
+	if deterministic {
+		// The API lists pods in no guaranteed order
+		sources = append([]logSource{}, sources...)
+		sort.Slice(sources, func(i, j int) bool { return sources[i].Pod < sources[j].Pod })
+	}
+	for _, src := range sources {
+		addSessionColor(src)
+	}
+}
+
+// Function to give the pods joining the session the next free colors
+func attachSessionColors(sources []logSource) {
+	sessionColorsMu.Lock()
+	defer sessionColorsMu.Unlock()
+	for _, src := range sources {
+		addSessionColor(src)
+	}
+}
+
+// Function to give a pod the next free color, with sessionColorsMu held
+func addSessionColor(src logSource) {
+	key := src.Namespace + "/" + src.Pod
+	if _, ok := sessionColors[key]; ok || sessionColorsHashed {
+		return
+	}
+	switch next := len(sessionColors); {
+	case next < len(podPalette):
+		sessionColors[key] = podPalette[next]
+	case next < len(podPalette)+len(extendedPalette):
+		sessionColors[key] = extendedPalette[next-len(podPalette)]
+	}
+}
+
+// Palette index of each workload, saved so a workload keeps its color across runs
+type colorAssignments struct {
+	once   sync.Once
+	mu     sync.Mutex
+	path   string
+	colors map[string]int
+}
+
+var workloadColors = &colorAssignments{}
+
+// Function to get the name of the workload owning a pod, without the ReplicaSet hash
+func workloadName(pod v1.Pod) string {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Controller == nil || !*owner.Controller {
+			continue
+		}
+		if hash, ok := pod.Labels["pod-template-hash"]; ok && owner.Kind == "ReplicaSet" {
+			return strings.TrimSuffix(owner.Name, "-"+hash)
+		}
+		return owner.Name
+	}
+	return pod.Name
+}
+
+// Function to pick the color of a pod from its name or its workload
+func podColor(src logSource, opts Options) colorizer {
+	if opts.ColorBy == "workload" && src.Workload != "" {
+		// Colors remembered from previous runs differ from one machine to another
+		if opts.Deterministic {
+			return podPalette[hashIndex(src.Workload)]
+		}
+		return podPalette[workloadColors.index(src.Workload)]
+	}
+	sessionColorsMu.RLock()
+	color, ok := sessionColors[src.Namespace+"/"+src.Pod]
+	sessionColorsMu.RUnlock()
+	if ok {
+		return color
+	}
+	return podPalette[hashIndex(src.Pod)]
+}
+
+// Function to tell whether lines show the namespace of their pod
+func showNamespaces(opts Options) bool {
+	return opts.AllNamespaces || len(opts.Namespaces) > 1
+}
+
+// Function to tell whether lines are prefixed with their source
+func showPrefix(opts Options) bool {
+	return opts.AllPods || opts.AllContainers || showNamespaces(opts) || opts.PrefixTemplate != ""
+}
+
+// Function to build the colored [pod] prefix of a source, with the namespace before and the container
+// after when they are shown, each colored on its own, or else the --prefix-template of the source
+func sourcePrefix(src logSource, opts Options) string {
+	if opts.PrefixTemplate != "" {
+		return "[" + podColor(src, opts).Sprintf("%s", expandPrefixTemplate(opts.PrefixTemplate, src)) + "]"
+	}
+	color := podColor(src, opts)
+	prefix := color.Sprintf("%s", src.Pod)
+	if showNamespaces(opts) {
+		prefix = podPalette[hashIndex(src.Namespace)].Sprintf("%s/", src.Namespace) + prefix
+	}
+	if opts.AllContainers {
+		// Containers of a pod share its color
+		prefix += color.Sprintf("/%s", src.Container)
+	}
+	return "[" + prefix + "]"
+}
+
+func hashIndex(name string) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(name))
+	return int(hash.Sum32() % uint32(len(podPalette)))
+}
+
+func (c *colorAssignments) load() {
+	c.colors = make(map[string]int)
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return
+	}
+	c.path = filepath.Join(configDir, "klog", "colors.json")
+	if data, err := os.ReadFile(c.path); err == nil {
+		_ = json.Unmarshal(data, &c.colors)
+	}
+}
+
+// Function to get the remembered palette index of a workload, assigning and saving a new one if needed
+func (c *colorAssignments) index(workload string) int {
+	c.once.Do(c.load)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if index, ok := c.colors[workload]; ok && index < len(podPalette) {
+		return index
+	}
+
+	// A new workload takes the first color no remembered workload has, once all taken the one of its name
+	used := make(map[int]bool, len(c.colors))
+	for _, index := range c.colors {
+		used[index] = true
+	}
+	index := hashIndex(workload)
+	for i := range podPalette {
+		if !used[i] {
+			index = i
+			break
+		}
+	}
+	c.colors[workload] = index
+	if c.path != "" {
+		if data, err := json.MarshalIndent(c.colors, "", "  "); err == nil {
+			if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err == nil {
+				_ = os.WriteFile(c.path, data, 0o600)
+			}
+		}
+	}
+	return index
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/pterm/pterm"
+)
+
+// Number of displayed lines kept for runtime commands
+const historySize = 200
+
+// Keyword highlighted in the next lines, -k or the one typed while streaming
+var liveKeyword atomic.Pointer[keywordHighlight]
+
+// Keyword as typed, its regex, and the regex compiled once with the highlight rules
+type keywordHighlight struct {
+	raw     string
+	pattern string
+	re      *regexp.Regexp
+}
+
+// Keep the last displayed lines
+type lineHistory struct {
+	mu    sync.Mutex
+	lines []printedLine
+}
+
+var history = &lineHistory{}
+
+func (h *lineHistory) add(line printedLine) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lines = append(h.lines, line)
+	if len(h.lines) > historySize {
+		h.lines = h.lines[len(h.lines)-historySize:]
+	}
+}
+
+// Function to find the most recent line accepted by match
+func (h *lineHistory) last(match func(printedLine) bool) (printedLine, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := len(h.lines) - 1; i >= 0; i-- {
+		if match(h.lines[i]) {
+			return h.lines[i], true
+		}
+	}
+	return printedLine{}, false
+}
+
+func (h *lineHistory) snapshot() []printedLine {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]printedLine{}, h.lines...)
+}
+
+// Function to read runtime commands typed in the terminal while streaming
+func startCommands(opts Options) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return
+	}
+
+	pterm.Info.Println("Type ? and Enter to list runtime commands")
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			runCommand(strings.TrimSpace(scanner.Text()), opts)
+		}
+	}()
+}
+
+// Function to apply a runtime command
+func runCommand(command string, opts Options) {
+	switch {
+	case command == "?":
+		pterm.Info.Println("Runtime commands:\n" +
+			"  /<keyword>  highlight <keyword> in the next lines (/ alone clears it)\n" +
+			"  r           render the recent lines again with the current keyword\n" +
+			"  y [regex]   copy the last line matching regex (default: keyword, else last error) to the clipboard\n" +
+			"  s [regex]   copy the last matching line and its context as a Markdown snippet with its origin\n" +
+			"  o [regex]   print the --dashboard URLs for the context of the last matching line\n" +
+			"  l           print the legend of colors, keyword and filters\n" +
+			"  i [regex]   show the raw text, parsed fields, origin and level rule of the last matching line\n" +
+			"  mute <n>    hide the lines of the n-th stream of the legend, counting them (again to unmute)\n" +
+			"  solo <n>    only display the n-th stream of the legend, counting the lines of the others (again to end)\n" +
+			"  <n>         only display the n-th stream of the legend, the others held back until <n> again\n" +
+			"  only pods|containers|nodes [regex]   only display lines whose metadata matches regex (no regex shows all)")
+	case strings.HasPrefix(command, "/"):
+		setLiveKeyword(strings.TrimPrefix(command, "/"), opts)
+	case command == "r":
+		rerenderHistory(opts)
+	case opts.MetadataOnly && (command == "y" || command == "i" || command == "s" || strings.HasPrefix(command, "y ") || strings.HasPrefix(command, "i ") || strings.HasPrefix(command, "s ")):
+		pterm.Warning.Println("The content of the lines is hidden with --metadata-only")
+	case command == "y" || strings.HasPrefix(command, "y "):
+		copyLastLine(strings.TrimSpace(strings.TrimPrefix(command, "y")), opts)
+	case command == "s" || strings.HasPrefix(command, "s "):
+		copySnippet(strings.TrimSpace(strings.TrimPrefix(command, "s")), opts)
+	case command == "l":
+		printLegend(opts)
+	case command == "i" || strings.HasPrefix(command, "i "):
+		inspectLine(strings.TrimSpace(strings.TrimPrefix(command, "i")), opts)
+	case command == "o" || strings.HasPrefix(command, "o "):
+		printDashboards(strings.TrimSpace(strings.TrimPrefix(command, "o")), opts)
+	case strings.HasPrefix(command, "mute "):
+		toggleMute(strings.TrimSpace(strings.TrimPrefix(command, "mute ")))
+	case strings.HasPrefix(command, "solo "):
+		toggleSolo(strings.TrimSpace(strings.TrimPrefix(command, "solo ")))
+	case strings.HasPrefix(command, "only "):
+		kind, pattern, _ := strings.Cut(strings.TrimPrefix(command, "only "), " ")
+		setMetadataFilter(kind, strings.TrimSpace(pattern))
+	case command != "":
+		if n, err := strconv.Atoi(command); err == nil {
+			toggleZoom(n, opts)
+			return
+		}
+		pterm.Warning.Printf("Unknown command '%s', type ? for help\n", command)
+	}
+}
+
+func setLiveKeyword(keyword string, opts Options) {
+	pattern, err := keywordPattern(keyword, opts)
+	if err != nil {
+		pterm.Error.Printf("Invalid keyword: %v\n", err)
+		return
+	}
+
+	liveKeyword.Store(newKeywordHighlight(keyword, pattern))
+	if keyword == "" {
+		pterm.Info.Println("Keyword highlighting disabled")
+	} else {
+		pterm.Info.Printf("Highlighting keyword '%s'\n", keyword)
+	}
+}
+
+// Function to print the recent lines again with the current keyword
+func rerenderHistory(opts Options) {
+	keyword := currentKeyword()
+	lines := history.snapshot()
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	pterm.Info.Printf("Rendering the last %d lines again\n", len(lines))
+	for _, line := range lines {
+		fmt.Println(line.render(keyword))
+	}
+}
+
+func currentKeyword() *keywordHighlight {
+	if live := liveKeyword.Load(); live != nil {
+		return live
+	}
+	return &keywordHighlight{}
+}
+
+// Function to highlight the -k keyword, once the highlight rules are loaded
+func initKeyword(opts Options) {
+	pattern, err := keywordPattern(opts.Keyword, opts)
+	if err != nil {
+		pterm.Error.Printf("Invalid keyword: %v\n", err)
+		os.Exit(1)
+	}
+	liveKeyword.Store(newKeywordHighlight(opts.Keyword, pattern))
+}
+
+// Function to compile the regex of a keyword with the highlight rules, none when both are empty
+func newKeywordHighlight(raw string, pattern string) *keywordHighlight {
+	keyword := &keywordHighlight{raw: raw, pattern: pattern}
+	if highlighted := activeRules.highlighted(pattern); highlighted != "" {
+		// The patterns were validated with the keyword and the rules
+		keyword.re, _ = regexp.Compile(highlighted)
+	}
+	return keyword
+}
+
+// Function to get the regex highlighting a keyword: the keyword matched literally, or as a regex
+// with --keyword-regex, whatever its case with --ignore-case
+func keywordPattern(keyword string, opts Options) (string, error) {
+	if keyword == "" {
+		return "", nil
+	}
+	if !opts.KeywordRegex {
+		keyword = regexp.QuoteMeta(keyword)
+	} else if _, err := regexp.Compile(keyword); err != nil {
+		return "", fmt.Errorf("%w (without --keyword-regex the keyword is matched literally)", err)
+	}
+	if opts.IgnoreCase {
+		keyword = "(?i:" + keyword + ")"
+	}
+	return keyword, nil
+}
+
+// Function to find the last line matching pattern, the keyword or else the last error
+func selectLine(pattern string, opts Options) (printedLine, bool) {
+	if pattern == "" {
+		pattern = currentKeyword().pattern
+	}
+
+	match := func(line printedLine) bool { return line.level == "error" }
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			pterm.Error.Printf("Invalid pattern: %v\n", err)
+			return printedLine{}, false
+		}
+		match = func(line printedLine) bool { return re.MatchString(line.line) }
+	}
+
+	line, ok := history.last(match)
+	if !ok {
+		pterm.Warning.Println("No matching line in the recent lines")
+	}
+	return line, ok
+}
+
+// Function to copy the last matching line with its pod and timestamp to the clipboard
+func copyLastLine(pattern string, opts Options) {
+	line, ok := selectLine(pattern, opts)
+	if !ok {
+		return
+	}
+
+	text := fmt.Sprintf("%s/%s %s %s", line.src.Pod, line.src.Container, line.time.Format(time.RFC3339Nano), line.line)
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	// OSC 52 asks the terminal to set the system clipboard
+	fmt.Printf("\033]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	pterm.Success.Printf("Copied to clipboard: %s\n", text)
+}
+
+// Function to print the details of the last matching line: raw text, parsed fields, origin and level rule
+func inspectLine(pattern string, opts Options) {
+	line, ok := selectLine(pattern, opts)
+	if !ok {
+		return
+	}
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	pterm.Info.Println("Line details")
+	fmt.Printf("  raw:     %s\n", line.raw)
+	fmt.Printf("  source:  %s/%s, container %s, node %s\n", line.src.Namespace, line.src.Pod, line.src.Container, line.src.Node)
+	fmt.Printf("  time:    %s\n", line.time.Format(time.RFC3339Nano))
+	fmt.Printf("  level:   %s (%s)\n", levelColor(line.level)(line.level), line.rule)
+	if len(line.fields) > 0 {
+		if data, err := json.MarshalIndent(line.fields, "  ", "  "); err == nil {
+			fmt.Printf("  fields:  %s\n", data)
+		}
+	}
+}
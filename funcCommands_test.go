@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestKeywordPattern(t *testing.T) {
+	tests := []struct {
+		keyword string
+		opts    Options
+		want    string
+		wantErr bool
+	}{
+		{"", Options{}, "", false},
+		{"timeout", Options{}, "timeout", false},
+		{"a.b[c]*", Options{}, `a\.b\[c\]\*`, false},
+		{"timeout|refused", Options{}, `timeout\|refused`, false},
+		{"timeout|refused", Options{KeywordRegex: true}, "timeout|refused", false},
+		{"timeout", Options{IgnoreCase: true}, "(?i:timeout)", false},
+		{"a+b", Options{IgnoreCase: true}, `(?i:a\+b)`, false},
+		{"5\\d\\d", Options{KeywordRegex: true, IgnoreCase: true}, "(?i:5\\d\\d)", false},
+		{"unclosed(", Options{}, `unclosed\(`, false},
+		{"unclosed(", Options{KeywordRegex: true}, "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := keywordPattern(tt.keyword, tt.opts)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("keywordPattern(%q, regex=%v, ignore-case=%v) = %q, %v, want %q, error %v",
+				tt.keyword, tt.opts.KeywordRegex, tt.opts.IgnoreCase, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// Number of templates shown in the comparison table
+const compareRows = 30
+
+var compareCmd = &cobra.Command{
+	Use:     "compare <pod-name>",
+	Short:   "Compare message templates of matching pods between two time windows.",
+	Example: "  klog compare my-api --window-a '14:00-14:05' --window-b '15:00-15:05'",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		compare(args[0], flags)
+	},
+}
+
+func init() {
+	compareCmd.Flags().StringVar(&flags.WindowA, "window-a", "", "First window, 'HH:MM-HH:MM' today or 'RFC3339/RFC3339'")
+	compareCmd.Flags().StringVar(&flags.WindowB, "window-b", "", "Second window, 'HH:MM-HH:MM' today or 'RFC3339/RFC3339'")
+	_ = compareCmd.MarkFlagRequired("window-a")
+	_ = compareCmd.MarkFlagRequired("window-b")
+}
+
+// Function to parse a window written '14:00-14:05' (today, local time) or '<RFC3339>/<RFC3339>'
+func parseWindow(window string) (time.Time, time.Time, error) {
+	if start, end, found := strings.Cut(window, "/"); found {
+		from, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		to, err := time.Parse(time.RFC3339, end)
+		return from, to, err
+	}
+
+	start, end, found := strings.Cut(window, "-")
+	if !found {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid window %q", window)
+	}
+	from, err := clockToday(start)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	to, err := clockToday(end)
+	return from, to, err
+}
+
+func clockToday(clock string) (time.Time, error) {
+	t, err := time.ParseInLocation("15:04", strings.TrimSpace(clock), time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, time.Local), nil
+}
+
+// Function to count the message templates logged by the pods between start and end
+func countTemplates(ctx context.Context, clientset *kubernetes.Clientset, pods []v1.Pod, opts Options, start time.Time, end time.Time) (map[string]int, error) {
+	counts := make(map[string]int)
+	sinceTime := metav1.NewTime(start)
+
+	for _, pod := range pods {
+		podLogOptions := &v1.PodLogOptions{
+			Container:  podContainer(pod, opts),
+			SinceTime:  &sinceTime,
+			Timestamps: true,
+		}
+
+		stream, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, podLogOptions).Stream(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		scanner := newLineScanner(stream)
+		for scanner.Scan() {
+			timestamp, line, _ := strings.Cut(scanner.Text(), " ")
+			if t, err := time.Parse(time.RFC3339Nano, timestamp); err == nil && t.After(end) {
+				break
+			}
+			counts[normalizeMessage(line)]++
+		}
+		stream.Close()
+	}
+	return counts, nil
+}
+
+// Function to print the templates whose count changed the most between the windows
+func compare(pod string, opts Options) {
+	startA, endA, err := parseWindow(opts.WindowA)
+	if err != nil {
+		pterm.Error.Printf("Invalid --window-a: %v\n", err)
+		os.Exit(1)
+	}
+	startB, endB, err := parseWindow(opts.WindowB)
+	if err != nil {
+		pterm.Error.Printf("Invalid --window-b: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	clientset := newClientset(opts)
+	matchedPods := findPods(ctx, clientset, pod, opts)
+
+	countsA, err := countTemplates(ctx, clientset, matchedPods, opts, startA, endA)
+	if err != nil {
+		pterm.Error.Printf("Error fetching window A: %v\n", err)
+		os.Exit(1)
+	}
+	countsB, err := countTemplates(ctx, clientset, matchedPods, opts, startB, endB)
+	if err != nil {
+		pterm.Error.Printf("Error fetching window B: %v\n", err)
+		os.Exit(1)
+	}
+
+	templates := make(map[string]struct{})
+	for template := range countsA {
+		templates[template] = struct{}{}
+	}
+	for template := range countsB {
+		templates[template] = struct{}{}
+	}
+
+	sorted := make([]string, 0, len(templates))
+	for template := range templates {
+		sorted = append(sorted, template)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		di, dj := abs(countsB[sorted[i]]-countsA[sorted[i]]), abs(countsB[sorted[j]]-countsA[sorted[j]])
+		if di != dj {
+			return di > dj
+		}
+		return sorted[i] < sorted[j]
+	})
+	if len(sorted) > compareRows {
+		sorted = sorted[:compareRows]
+	}
+
+	table := pterm.TableData{{"A", "B", "Change", "Template"}}
+	for _, template := range sorted {
+		a, b := countsA[template], countsB[template]
+		change := fmt.Sprintf("%+d", b-a)
+		switch {
+		case a == 0:
+			change = pterm.Red("NEW")
+		case b == 0:
+			change = pterm.Green("GONE")
+		}
+		table = append(table, []string{fmt.Sprint(a), fmt.Sprint(b), change, template})
+	}
+
+	pterm.Info.Printf("Comparing %d pods: A %s → %s, B %s → %s\n", len(matchedPods),
+		startA.Format(timestampFormat), endA.Format(timestampFormat), startB.Format(timestampFormat), endB.Format(timestampFormat))
+	_ = pterm.DefaultTable.WithHasHeader().WithData(table).Render()
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseWindow(t *testing.T) {
+	now := time.Now()
+	today := func(hour, minute int) time.Time {
+		return time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		window   string
+		from, to time.Time
+		wantErr  bool
+	}{
+		{"14:00-14:05", today(14, 0), today(14, 5), false},
+		{" 9:30 - 10:00 ", today(9, 30), today(10, 0), false},
+		{"2024-03-01T10:00:00Z/2024-03-01T10:05:00Z", time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 10, 5, 0, 0, time.UTC), false},
+		{"2024-03-01T10:00:00+02:00/2024-03-01T10:05:00+02:00", time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 8, 5, 0, 0, time.UTC), false},
+		{"14:00", time.Time{}, time.Time{}, true},
+		{"14:00-25:00", time.Time{}, time.Time{}, true},
+		{"yesterday-today", time.Time{}, time.Time{}, true},
+		{"2024-03-01/2024-03-02", time.Time{}, time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		from, to, err := parseWindow(tt.window)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseWindow(%q) = %s, %s, want an error", tt.window, from, to)
+			}
+			continue
+		}
+		if err != nil || !from.Equal(tt.from) || !to.Equal(tt.to) {
+			t.Errorf("parseWindow(%q) = %s, %s, %v, want %s, %s", tt.window, from, to, err, tt.from, tt.to)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/pterm/pterm"
+)
+
+// Key of the ConfigMap read when the --config source doesn't name one
+const configMapKey = "config.yaml"
+
+// How long to wait for a remote config before using the cached copy
+const configFetchTimeout = 10 * time.Second
+
+// Function to read the shared config from a file, a URL or configmap://<namespace>/<name>[/<key>]
+func fetchConfig(ctx context.Context, source string, opts Options) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(source, "configmap://"):
+		parts := strings.Split(strings.TrimPrefix(source, "configmap://"), "/")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("invalid source '%s', use configmap://<namespace>/<name>[/<key>]", source)
+		}
+		configMap, err := newClientset(opts).CoreV1().ConfigMaps(parts[0]).Get(ctx, parts[1], metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		key := configMapKey
+		if len(parts) == 3 {
+			key = parts[2]
+		} else if len(configMap.Data) == 1 {
+			for only := range configMap.Data {
+				key = only
+			}
+		}
+		data, ok := configMap.Data[key]
+		if !ok {
+			keys := make([]string, 0, len(configMap.Data))
+			for k := range configMap.Data {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			return nil, fmt.Errorf("ConfigMap %s/%s has no key '%s' (keys: %s)", parts[0], parts[1], key, strings.Join(keys, ", "))
+		}
+		return []byte(data), nil
+
+	case strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://"):
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+		if err != nil {
+			return nil, err
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			return nil, err
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetching %s: %s", source, response.Status)
+		}
+		return io.ReadAll(response.Body)
+
+	default:
+		return os.ReadFile(source)
+	}
+}
+
+// Function to get the file keeping the last copy of a shared config
+func configCachePath(source string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(source))
+	return filepath.Join(cacheDir, "klog", fmt.Sprintf("config-%08x.yaml", hash.Sum32())), nil
+}
+
+// Function to load the shared config of --config, falling back to the last fetched copy when unreachable
+func loadSharedConfig(opts Options) (ruleFile, error) {
+	var config ruleFile
+	if opts.Config == "" {
+		return config, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), configFetchTimeout)
+	defer cancel()
+
+	cachePath, cacheErr := configCachePath(opts.Config)
+	data, err := fetchConfig(ctx, opts.Config, opts)
+	switch {
+	case err == nil && cacheErr == nil:
+		// Failing to write the cache never prevents streaming
+		if err := os.MkdirAll(filepath.Dir(cachePath), 0o700); err == nil {
+			_ = os.WriteFile(cachePath, data, 0o600)
+		}
+	case err != nil && cacheErr == nil:
+		cached, cachedErr := os.ReadFile(cachePath)
+		if cachedErr != nil {
+			return config, fmt.Errorf("fetching %s: %w", opts.Config, err)
+		}
+		pterm.Warning.Printf("Unable to fetch config %s, using the copy of the last run: %v\n", opts.Config, err)
+		data = cached
+	case err != nil:
+		return config, fmt.Errorf("fetching %s: %w", opts.Config, err)
+	}
+
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("parsing %s: %w", opts.Config, err)
+	}
+	return config, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pterm/pterm"
+)
+
+// Function to get the kubeconfig context named by --context, asking when several contexts contain the name
+func resolveContext(opts Options) string {
+	if opts.Context == "" {
+		return ""
+	}
+
+	// Unreadable kubeconfigs are reported when creating the client
+	rawConfig, err := kubeClientConfig(Options{Kubeconfig: opts.Kubeconfig}).RawConfig()
+	if err != nil {
+		return opts.Context
+	}
+	if _, ok := rawConfig.Contexts[opts.Context]; ok {
+		return opts.Context
+	}
+
+	var matches []string
+	for name := range rawConfig.Contexts {
+		if strings.Contains(name, opts.Context) {
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+
+	switch {
+	case len(matches) == 0:
+		pterm.Error.Printf("No kubeconfig context matching '%s'\n", opts.Context)
+		os.Exit(1)
+	case len(matches) == 1:
+		return matches[0]
+	case opts.NonInteractive:
+		pterm.Error.Printf("%d contexts match '%s' (%s), use the full name\n", len(matches), opts.Context, strings.Join(matches, ", "))
+		os.Exit(1)
+	}
+
+	selectorContext := pterm.DefaultInteractiveSelect.WithDefaultText("Select a context")
+	selectorContext.MaxHeight = 10
+	selectedOption, _ := selectorContext.WithOptions(matches).Show()
+
+	fmt.Print("\033[F\033[K\033[F\033[K") // Remove last 2 lines
+	return selectedOption
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// Rank of the levels for set-level, panic lines being as severe as errors
+var levelRanks = map[string]int{"debug": 0, "info": 1, "warning": 2, "panic": 3, "error": 3}
+
+var (
+	// Lowest level displayed, set with klog ctl set-level
+	minimumLevel atomic.Int32
+	// Function attaching the pods matching a regex to a -a session, nil for a single stream
+	controlAttach func(pattern string) (int, error)
+)
+
+var ctlCmd = &cobra.Command{
+	Use:   "ctl set-level <level>|add-pod <regex>|dump-buffer",
+	Short: "Adjust a running session started with --control from another terminal.",
+	Example: "  klog ctl set-level error\n" +
+		"  klog ctl add-pod 'worker.*'\n" +
+		"  klog ctl dump-buffer --pid 4242",
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		sendControl(strings.Join(args, " "), flags)
+	},
+}
+
+func init() {
+	ctlCmd.Flags().IntVar(&flags.ControlPid, "pid", 0, "Process ID of the session, required when several sessions run")
+}
+
+// Function to get the socket of the session of a process
+func controlSocketPath(pid int) string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("klog-%d.sock", pid))
+}
+
+// Function to listen for klog ctl commands with --control
+func startControl(opts Options) {
+	if !opts.Control {
+		return
+	}
+
+	path := controlSocketPath(os.Getpid())
+	// A socket left by a killed process of the same ID
+	_ = os.Remove(path)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		pterm.Error.Printf("Error opening control socket: %v\n", err)
+		os.Exit(1)
+	}
+
+	onSessionEnd(func() { listener.Close() })
+
+	pterm.Info.Printf("Control this session from another terminal with 'klog ctl --pid %d'\n", os.Getpid())
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveControl(conn, opts)
+		}
+	}()
+}
+
+// Function to run the command of a klog ctl connection and answer it
+func serveControl(conn net.Conn, opts Options) {
+	defer conn.Close()
+
+	command, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return
+	}
+	name, arg, _ := strings.Cut(strings.TrimSpace(command), " ")
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case "set-level":
+		rank, ok := parseLevel(arg)
+		if !ok {
+			fmt.Fprintf(conn, "error: invalid level '%s', use debug, info, warning or error\n", arg)
+			return
+		}
+		minimumLevel.Store(int32(rank))
+		pterm.Info.Printf("Displaying %s lines and above\n", arg)
+		fmt.Fprintf(conn, "displaying %s lines and above\n", arg)
+
+	case "add-pod":
+		if controlAttach == nil {
+			fmt.Fprintln(conn, "error: pods can only be added to a session started with -a or --all-containers")
+			return
+		}
+		attached, err := controlAttach(arg)
+		if err != nil {
+			fmt.Fprintf(conn, "error: %v\n", err)
+			return
+		}
+		fmt.Fprintf(conn, "%d pods attached\n", attached)
+
+	case "dump-buffer":
+		keyword := currentKeyword()
+		for _, line := range history.snapshot() {
+			fmt.Fprintln(conn, line.render(keyword))
+		}
+
+	default:
+		fmt.Fprintf(conn, "error: unknown command '%s', use set-level, add-pod or dump-buffer\n", name)
+	}
+}
+
+// Function to get the rank of a level given as warn, warning or warning+ (the level and above)
+func parseLevel(level string) (int, bool) {
+	level = strings.TrimSuffix(strings.ToLower(level), "+")
+	switch level {
+	case "warn":
+		level = "warning"
+	case "err":
+		level = "error"
+	}
+	rank, ok := levelRanks[level]
+	return rank, ok
+}
+
+// Function to apply --min-level as the lowest level displayed
+func initMinimumLevel(opts Options) {
+	if opts.Level == "" {
+		return
+	}
+	rank, ok := parseLevel(opts.Level)
+	if !ok {
+		pterm.Error.Printf("Invalid level '%s', use debug, info, warning or error\n", opts.Level)
+		os.Exit(1)
+	}
+	minimumLevel.Store(int32(rank))
+}
+
+// Function to get the lowest level displayed, empty when every line is
+func minimumLevelName() string {
+	switch minimumLevel.Load() {
+	case 1:
+		return "info"
+	case 2:
+		return "warning"
+	case 3:
+		return "error"
+	}
+	return ""
+}
+
+// Function to tell whether a line is below the level set with --min-level or klog ctl set-level
+func belowMinimumLevel(level string) bool {
+	return int32(levelRanks[level]) < minimumLevel.Load()
+}
+
+// Function to attach the pods matching the regex that the session doesn't stream yet
+func attachMatchingPods(ctx context.Context, clientset *kubernetes.Clientset, pattern string, opts Options, attach func(v1.Pod)) (int, error) {
+	streamed := make(map[string]bool)
+	outputMu.Lock()
+	for _, src := range sessionSources {
+		streamed[src.Namespace+"/"+src.Pod] = true
+	}
+	outputMu.Unlock()
+
+	namespaces := searchNamespaces(opts)
+	var pods []v1.Pod
+	for _, namespace := range namespaces {
+		list, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: opts.Selector, FieldSelector: opts.FieldSelector})
+		if err != nil {
+			return 0, err
+		}
+		pods = append(pods, list.Items...)
+	}
+	matched, err := matchTarget(ctx, clientset, pods, pattern, namespaces, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	attached := 0
+	for _, pod := range matched {
+		if streamed[pod.Namespace+"/"+pod.Name] || pod.DeletionTimestamp != nil || (!opts.Force && activeRules.protectedNamespace(pod.Namespace)) {
+			continue
+		}
+		pterm.Info.Printf("Pod '%s' added by klog ctl, attaching its logs\n", pod.Name)
+		attach(pod)
+		attached++
+	}
+	return attached, nil
+}
+
+// Function to send a command to a running session and print its answer
+func sendControl(command string, opts Options) {
+	pid := opts.ControlPid
+	if pid == 0 {
+		sockets, _ := filepath.Glob(filepath.Join(os.TempDir(), "klog-*.sock"))
+		switch len(sockets) {
+		case 0:
+			pterm.Error.Println("No session started with --control found")
+			os.Exit(1)
+		case 1:
+		default:
+			pterm.Error.Printf("%d sessions started with --control found, choose one with --pid\n", len(sockets))
+			os.Exit(1)
+		}
+		pid, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(sockets[0]), "klog-"), ".sock"))
+	}
+
+	conn, err := net.Dial("unix", controlSocketPath(pid))
+	if err != nil {
+		pterm.Error.Printf("Error connecting to session %d: %v\n", pid, err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	fmt.Fprintln(conn, command)
+	scanner := newLineScanner(conn)
+	failed := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if message, ok := strings.CutPrefix(line, "error: "); ok {
+			pterm.Error.Println(message)
+			failed = true
+			continue
+		}
+		fmt.Println(line)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,476 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+const (
+	timestampFormat = "2006-01-02T15:04:05.000"
+	errorKeywords   = "level=error|level=err|levelerror|err=|[error]|[ERROR]|[err]|[ERR]| ERRO: | Err: | ERR | ERROR | CRIT "
+	warningKeywords = "level=warning|level=warn|levelwarn|warn=|[warning]|[WARNING]|[warn]|[WARN]| WARN: | WARN | WARNING "
+	panicKeywords   = "level=panic|levelpanic|[panic]|[PANIC]| panic:|PANIC "
+	debugKeywords   = "level=debug|leveldebug|[debug]|[DEBUG]| debug:|DEBUG "
+
+	errorLevelJson = "error|critical|fatal|alert|emergency"
+	warnLevelJson  = "warn|warning|panic"
+	debugLevelJson = "debug"
+)
+
+// Function to highlight a word in the string
+func highlightKeyword(line string, re *regexp.Regexp, colorFunc func(a ...interface{}) string) string {
+	matches := re.FindAllStringIndex(line, -1)
+
+	if len(matches) > 0 {
+		result := ""
+		startIndex := 0
+		for _, match := range matches {
+			result += colorFunc(line[startIndex:match[0]]) + pterm.BgMagenta.Sprint(line[match[0]:match[1]])
+			startIndex = match[1]
+		}
+		result += colorFunc(line[startIndex:])
+		return result
+	}
+
+	return colorFunc(line)
+}
+
+// Color sequences of a rendered line
+var ansiSequence = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// Function to highlight the keyword over a line already colored field by field, the keyword being
+// matched on the visible text and its background kept through the colors of the fields
+func highlightRendered(text string, re *regexp.Regexp) string {
+	if !pterm.PrintColor {
+		return text
+	}
+
+	// Visible text, with the offset in text of each of its bytes
+	var visible strings.Builder
+	var offsets []int
+	sequences := ansiSequence.FindAllStringIndex(text, -1)
+	for i, next := 0, 0; i < len(text); {
+		if next < len(sequences) && sequences[next][0] == i {
+			i = sequences[next][1]
+			next++
+			continue
+		}
+		visible.WriteByte(text[i])
+		offsets = append(offsets, i)
+		i++
+	}
+
+	starts, ends := make(map[int]bool), make(map[int]bool)
+	for _, match := range re.FindAllStringIndex(visible.String(), -1) {
+		if match[0] < match[1] {
+			starts[offsets[match[0]]] = true
+			ends[offsets[match[1]-1]+1] = true
+		}
+	}
+	if len(starts) == 0 {
+		return text
+	}
+
+	var out strings.Builder
+	highlighted := false
+	for i, next := 0, 0; i <= len(text); {
+		if ends[i] {
+			out.WriteString("\x1b[49m")
+			highlighted = false
+		}
+		if starts[i] {
+			out.WriteString("\x1b[45m")
+			highlighted = true
+		}
+		if i == len(text) {
+			break
+		}
+		if next < len(sequences) && sequences[next][0] == i {
+			out.WriteString(text[i:sequences[next][1]])
+			// The reset closing a field also resets the background
+			if highlighted {
+				out.WriteString("\x1b[45m")
+			}
+			i = sequences[next][1]
+			next++
+			continue
+		}
+		out.WriteByte(text[i])
+		i++
+	}
+	return out.String()
+}
+
+func containsAny(line string, substrings ...string) bool {
+	return firstContained(line, substrings...) != ""
+}
+
+// Function to get the first of the substrings found in the line
+func firstContained(line string, substrings ...string) string {
+	for _, s := range substrings {
+		if strings.Contains((line), s) {
+			return s
+		}
+	}
+	return ""
+}
+
+// Function to detect the level of a log line from its keywords or JSON level field
+func detectLevel(line string) (string, map[string]interface{}) {
+	level, _, logEntry := classifyLine(line, activeRules.levelKeys)
+	return level, logEntry
+}
+
+// Function to classify a log line, also describing the rule that decided its level
+func classifyLine(line string, levelKeys []string) (string, string, map[string]interface{}) {
+	var logEntry map[string]interface{}
+	level, rule := "info", "no level keyword"
+
+	for _, keywords := range []struct{ level, list string }{
+		{"error", errorKeywords}, {"warning", warningKeywords}, {"panic", panicKeywords}, {"debug", debugKeywords},
+	} {
+		if keyword := firstContained(line, strings.Split(keywords.list, "|")...); keyword != "" {
+			level, rule = keywords.level, fmt.Sprintf("keyword %q", keyword)
+			break
+		}
+	}
+
+	if err := json.Unmarshal([]byte(line), &logEntry); err == nil {
+		if jsonLevel, exists := levelField(logEntry, levelKeys); exists {
+			level, rule = fieldLevel(jsonLevel), fmt.Sprintf("JSON level field %q", jsonLevel)
+		}
+	} else if pairs, ok := parseLogfmt(line); ok {
+		// The level key of logfmt wins over keywords found in the other values, e.g. err=
+		logEntry = make(map[string]interface{}, len(pairs))
+		for _, pair := range pairs {
+			logEntry[pair.key] = pair.value
+			if logfmtLevelKeys[pair.key] || slices.Contains(levelKeys, pair.key) {
+				level, rule = fieldLevel(pair.value), fmt.Sprintf("logfmt level field %q", pair.value)
+				// As the level=panic keyword did
+				if strings.EqualFold(pair.value, "panic") {
+					level = "panic"
+				}
+			}
+		}
+	}
+	return level, rule, logEntry
+}
+
+// Function to map the value of a level field to a level
+func fieldLevel(value string) string {
+	levelLower := strings.ToLower(value)
+	switch {
+	case containsAny(levelLower, strings.Split(errorLevelJson, "|")...):
+		return "error"
+	case containsAny(levelLower, strings.Split(warnLevelJson, "|")...):
+		return "warning"
+	case containsAny(levelLower, strings.Split(debugLevelJson, "|")...):
+		return "debug"
+	default:
+		return "info"
+	}
+}
+
+// Function to find the level of a JSON line with the keys holding it in order, a key being a flat key
+// like log.level or else a dotted path
+func levelField(logEntry map[string]interface{}, levelKeys []string) (string, bool) {
+	for _, key := range levelKeys {
+		if value, ok := lookupField(logEntry, key).(string); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// Keys of the logfmt fields holding the level, the message and the error
+var (
+	logfmtLevelKeys   = map[string]bool{"level": true, "lvl": true, "severity": true}
+	logfmtMessageKeys = map[string]bool{"msg": true, "message": true}
+	logfmtErrorKeys   = map[string]bool{"err": true, "error": true}
+)
+
+// Field of a logfmt line, with the text of its value as logged
+type logfmtPair struct {
+	key   string
+	value string
+	text  string
+}
+
+// Function to parse a line made only of key=value fields, values possibly quoted
+func parseLogfmt(line string) ([]logfmtPair, bool) {
+	var pairs []logfmtPair
+	for i := 0; i < len(line); {
+		if line[i] == ' ' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' && line[i] != '"' {
+			i++
+		}
+		if i == start || i == len(line) || line[i] != '=' {
+			return nil, false
+		}
+		pair := logfmtPair{key: line[start:i]}
+		i++
+
+		start = i
+		if i < len(line) && line[i] == '"' {
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(line) {
+				return nil, false
+			}
+			i++
+			pair.text = line[start:i]
+			value, err := strconv.Unquote(pair.text)
+			if err != nil {
+				return nil, false
+			}
+			pair.value = value
+		} else {
+			for i < len(line) && line[i] != ' ' {
+				i++
+			}
+			pair.text = line[start:i]
+			pair.value = pair.text
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, len(pairs) >= 2
+}
+
+// Function to color the keys of a logfmt line apart from their values, errors in red
+func renderLogfmt(pairs []logfmtPair, colorFunc func(a ...interface{}) string) string {
+	fields := make([]string, len(pairs))
+	for i, pair := range pairs {
+		valueColor := colorFunc
+		if logfmtErrorKeys[pair.key] && pair.value != "" {
+			valueColor = pterm.Red
+		}
+		fields[i] = pterm.FgDarkGray.Sprint(pair.key+"=") + valueColor(pair.text)
+	}
+	return strings.Join(fields, " ")
+}
+
+func levelColor(level string) func(a ...interface{}) string {
+	switch level {
+	case "error":
+		return pterm.Red
+	case "warning", "panic":
+		return pterm.Yellow
+	case "debug":
+		return pterm.Cyan
+	default:
+		return pterm.White
+	}
+}
+
+func printLogLine(src logSource, line string, opts Options) {
+	var timestamp string
+	rawLine := line
+	lineTime := time.Now()
+
+	if withTimestamps(opts) {
+		// Extract timestamp and rest of the line
+		if parts := strings.SplitN(line, " ", 2); len(parts) == 2 {
+			timestamp = parts[0]
+			line = parts[1]
+		}
+	}
+
+	// Lines forwarded by a sidecar are processed as the application logged them
+	line, unwrapped := activeRules.unwrap(line)
+
+	level, rule, fields := activeRules.classify(line)
+	session.count(level)
+	if timeline != nil {
+		timeline.observe(src, level)
+	}
+	resetIdleTimer()
+
+	var prefix, tag string
+	if showPrefix(opts) {
+		prefix = sourcePrefix(src, opts) + " "
+		if opts.AllPods && opts.CrossPod && level != "info" && level != "debug" {
+			tag = duplicates.observe(src.Pod, line, level, opts)
+		}
+	}
+	if opts.NewErrors && templates.novel(line) && (level == "error" || level == "panic") {
+		tag += " " + pterm.BgRed.Sprint("NEW")
+	}
+	if tracked != nil {
+		tag += tracked.observe(fields)
+	}
+
+	// Convert timestamp string to time.Time object
+	if timestamp != "" {
+		t, err := time.Parse(time.RFC3339Nano, timestamp)
+		if err == nil {
+			if opts.Deterministic {
+				t = t.UTC()
+			} else if opts.AllPods {
+				// One pod with a wrong clock would scramble the merged timestamps
+				t = clockSkew.observe(src, t, lineTime, opts)
+			}
+			lineTime = t
+			timestamp = t.Format(timestampFormat)
+		}
+	}
+
+	// Timestamps kept for ordering only are not displayed
+	if opts.HideTimestamps || !opts.Timestamp {
+		timestamp = ""
+	}
+
+	// The time the application logged the line is closer to the event than the kubelet one
+	if t, ok := activeRules.eventTime(line, unwrapped); ok {
+		if opts.Deterministic {
+			t = t.UTC()
+		}
+		lineTime = t
+	}
+
+	if captures != nil {
+		captures.add(src, rawLine)
+	}
+	if shutdown, ok := shutdowns.Load(src); ok {
+		shutdown.(*shutdownCapture).add(rawLine)
+	}
+
+	if classified != nil {
+		classified.add(src, lineTime, level, line)
+	}
+
+	// Lines hidden by --only-* still count and feed the captures, like muted ones
+	if !sourceAllowed(src) || activeRules.muted(line) || excluded(line) || mutes.suppress(src) || belowMinimumLevel(level) {
+		return
+	}
+	if probes != nil && probes.observe(src, line) {
+		return
+	}
+
+	// Only the --fields of a JSON line are displayed
+	if projected, ok := projectFields(fields, opts.Fields); ok {
+		line = projected
+	}
+
+	printed := printedLine{src: src, time: lineTime, timestamp: timestamp, prefix: prefix, line: line, level: level, tag: tag, raw: rawLine, rule: rule, fields: fields, opts: opts}
+	if router != nil && !router.dispatch(printed) {
+		return
+	}
+	history.add(printed)
+	if zoom.hold(printed) {
+		return
+	}
+	if pairer != nil && pairer.hold(printed) {
+		return
+	}
+	if sorter != nil {
+		sorter.add(printed)
+		return
+	}
+
+	// Lines of concurrent streams must not interleave
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	displayLine(printed, currentKeyword())
+}
+
+// Step reported by a spinner, or by plain lines in deterministic mode
+type progress interface {
+	UpdateText(text string)
+	Success(message ...interface{})
+	Fail(message ...interface{})
+}
+
+type plainProgress struct{}
+
+func (plainProgress) UpdateText(text string)         { pterm.Info.Println(text) }
+func (plainProgress) Success(message ...interface{}) { pterm.Success.Println(message...) }
+func (plainProgress) Fail(message ...interface{})    { pterm.Error.Println(message...) }
+
+// Function to report a step with plain lines, e.g. when several steps run concurrently
+func startPlainProgress(text string) progress {
+	pterm.Info.Println(text)
+	return plainProgress{}
+}
+
+// Function to start reporting a step, without animation frames in deterministic or non-interactive mode
+func startProgress(text string, opts Options) progress {
+	if opts.Deterministic || opts.NonInteractive {
+		return startPlainProgress(text)
+	}
+	spinner, _ := pterm.DefaultSpinner.Start(text)
+	return spinner
+}
+
+// Line as displayed, kept to render it again
+type printedLine struct {
+	src       logSource
+	time      time.Time
+	timestamp string
+	prefix    string
+	line      string
+	level     string
+	tag       string
+	raw       string
+	rule      string
+	fields    map[string]interface{}
+	opts      Options
+}
+
+func (p printedLine) render(keyword *keywordHighlight) string {
+	colorFunc := levelColor(p.level)
+	// The content of the lines is hidden on shared screens with --metadata-only
+	if p.opts.MetadataOnly {
+		source := podColor(p.src, p.opts).Sprintf("%s", p.src.Pod) + "/" + p.src.Container
+		metadata := fmt.Sprintf("%s %s%s", source, colorFunc(fmt.Sprintf("%-7s %d bytes", p.level, len(p.line))), p.tag)
+		// The time of the line is the only context left, shown even without --timestamp
+		timestamp := p.timestamp
+		if timestamp == "" && !p.opts.HideTimestamps && !p.time.IsZero() {
+			timestamp = p.time.Format(timestampFormat)
+		}
+		if timestamp == "" {
+			return metadata
+		}
+		return fmt.Sprintf("%s %s", pterm.FgDarkGray.Sprint(timestamp), metadata)
+	}
+	highlighting := keyword != nil && keyword.re != nil
+
+	// Logfmt fields and pretty-printed JSON are colored first, the keyword being highlighted over them
+	coloredLine, structured := "", false
+	if pairs, ok := parseLogfmt(p.line); ok {
+		coloredLine, structured = renderLogfmt(pairs, colorFunc), true
+	}
+	// The pretty-printed lines follow the prefix of the first one
+	if p.opts.PrettyJSON {
+		if pretty, ok := renderPrettyJSON(p.line); ok {
+			coloredLine, structured = pretty, true
+		}
+	}
+
+	switch {
+	case structured && highlighting:
+		coloredLine = highlightRendered(coloredLine, keyword.re)
+	case highlighting:
+		// Apply colorization to the rest of the line, a single color per level around the keyword
+		coloredLine = highlightKeyword(colorFunc(p.line), keyword.re, colorFunc)
+	case !structured:
+		coloredLine = colorFunc(p.line)
+	}
+
+	// Print timestamp normally and the rest colored
+	return fmt.Sprintf("%s %s%s%s", pterm.FgDarkGray.Sprint(p.timestamp), p.prefix, linkReferences(coloredLine, revisionOf(p.src), p.opts.CodeURL), p.tag)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files from the current rendering")
+
+// Keyword highlighted when rendering each fixture, none by default
+var fixtureKeywords = map[string]string{
+	"access.log":  `" 5\d\d `,
+	"logfmt.log":  "timeout|trace_id=4bf",
+	"unicode.log": "東京|€",
+}
+
+// Rendered lines longer than this are recorded by size and hash to keep golden files readable
+const goldenLineLimit = 512
+
+// Stream the fixture lines are rendered for
+var fixtureSource = logSource{Namespace: "shop", Pod: "orders-7d4b9c-x2k8p", Container: "api", Workload: "orders", Node: "node-1"}
+
+// Function to classify and render every line of a fixture as printLogLine would, with the options
+// and rules of the flags under test
+func renderFixture(t *testing.T, path string, keyword string, opts Options, rules ruleFile) string {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	compiled, err := compileRules(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	highlight := newKeywordHighlight(keyword, keyword)
+
+	var out strings.Builder
+	scanner := newLineScanner(file)
+	for scanner.Scan() {
+		line, _ := compiled.unwrap(scanner.Text())
+		level, _, fields := compiled.classify(line)
+		if projected, ok := projectFields(fields, opts.Fields); ok {
+			line = projected
+		}
+		printed := printedLine{src: fixtureSource, line: line, level: level, opts: opts}
+		if showPrefix(opts) {
+			printed.prefix = sourcePrefix(fixtureSource, opts) + " "
+		}
+
+		rendered := printed.render(highlight)
+		if len(rendered) > goldenLineLimit {
+			rendered = fmt.Sprintf("<%d bytes sha256:%x>", len(rendered), sha256.Sum256([]byte(rendered)))
+		} else {
+			rendered = fmt.Sprintf("%q", rendered)
+		}
+		fmt.Fprintf(&out, "%-7s %s\n", level, rendered)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("reading %s: %v", path, err)
+	}
+	return out.String()
+}
+
+// Function to compare a rendering with its golden file, rewritten with -update
+func compareGolden(t *testing.T, got string, fixture string, golden string) {
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("rendering of %s differs from %s (run go test -update if intended)\ngot:\n%s", fixture, golden, got)
+	}
+}
+
+// Function to check the rendering of testdata/fixtures/flags/<name>.log with the options and rules
+// of a formatter flag against testdata/golden/flags/<name>.golden
+func checkFlagGolden(t *testing.T, name string, opts Options, rules ruleFile) {
+	pterm.EnableColor()
+
+	fixture := filepath.Join("testdata", "fixtures", "flags", name+".log")
+	got := renderFixture(t, fixture, "", opts, rules)
+	compareGolden(t, got, fixture, filepath.Join("testdata", "golden", "flags", name+".golden"))
+}
+
+func TestRenderGolden(t *testing.T) {
+	pterm.EnableColor()
+
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "fixtures", "*.log"))
+	if err != nil || len(fixtures) == 0 {
+		t.Fatalf("no fixture found: %v", err)
+	}
+
+	for _, fixture := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(fixture), ".log")
+		t.Run(name, func(t *testing.T) {
+			got := renderFixture(t, fixture, fixtureKeywords[filepath.Base(fixture)], Options{}, ruleFile{})
+			compareGolden(t, got, fixture, filepath.Join("testdata", "golden", name+".golden"))
+		})
+	}
+}
+
+func TestParseLogfmt(t *testing.T) {
+	tests := []struct {
+		line string
+		want []logfmtPair
+		ok   bool
+	}{
+		{`level=info msg=started`, []logfmtPair{{"level", "info", "info"}, {"msg", "started", "started"}}, true},
+		{`level=error msg="connection refused" db=orders`, []logfmtPair{{"level", "error", "error"}, {"msg", "connection refused", `"connection refused"`}, {"db", "orders", "orders"}}, true},
+		{`msg="say \"hi\"" empty=`, []logfmtPair{{"msg", `say "hi"`, `"say \"hi\""`}, {"empty", "", ""}}, true},
+		{`  level=warn   code=42  `, []logfmtPair{{"level", "warn", "warn"}, {"code", "42", "42"}}, true},
+		{`level=info`, nil, false},
+		{`plain text line`, nil, false},
+		{`level=info msg="unterminated`, nil, false},
+		{`level=info =value`, nil, false},
+		{`level=info "quoted"=key`, nil, false},
+		{``, nil, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseLogfmt(tt.line)
+		if ok != tt.ok || ok && fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("parseLogfmt(%q) = %v, %v, want %v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLevelKeyGolden(t *testing.T) {
+	checkFlagGolden(t, "level-key", Options{}, ruleFile{LevelKeys: []string{"severity", "log.level"}})
+}
+
+func TestMetadataOnlyGolden(t *testing.T) {
+	checkFlagGolden(t, "metadata-only", Options{MetadataOnly: true}, ruleFile{})
+}
+
+func TestMetadataOnlyTime(t *testing.T) {
+	pterm.DisableColor()
+	defer pterm.EnableColor()
+
+	lineTime := time.Date(2024, 3, 1, 10, 0, 1, 0, time.UTC)
+	printed := printedLine{src: fixtureSource, time: lineTime, line: "secret", level: "info", opts: Options{MetadataOnly: true}}
+	if got, want := printed.render(nil), "2024-03-01T10:00:01.000 orders-7d4b9c-x2k8p/api info    6 bytes"; got != want {
+		t.Errorf("render() = %q, want %q", got, want)
+	}
+
+	printed.opts.HideTimestamps = true
+	if got, want := printed.render(nil), "orders-7d4b9c-x2k8p/api info    6 bytes"; got != want {
+		t.Errorf("render() with --hide-timestamps = %q, want %q", got, want)
+	}
+}
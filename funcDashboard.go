@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// Time range around a line used for the {from} and {to} placeholders
+const dashboardWindow = 5 * time.Minute
+
+// Function to fill a dashboard URL template with the context of a line
+func dashboardURL(template string, line printedLine) string {
+	// Values of the stream are not expanded again, e.g. a label holding {time}
+	return expandSourceTemplate(template, line.src, url.QueryEscape, map[string]string{
+		"revision": url.QueryEscape(revisionOf(line.src)),
+		"time":     url.QueryEscape(line.time.Format(time.RFC3339)),
+		"from":     strconv.FormatInt(line.time.Add(-dashboardWindow).UnixMilli(), 10),
+		"to":       strconv.FormatInt(line.time.Add(dashboardWindow).UnixMilli(), 10),
+	})
+}
+
+// Function to print the dashboards configured with --dashboard for the last matching line
+func printDashboards(pattern string, opts Options) {
+	if len(opts.Dashboards) == 0 {
+		pterm.Warning.Println("No dashboard configured, use --dashboard name=url-template")
+		return
+	}
+
+	line, ok := selectLine(pattern, opts)
+	if !ok {
+		return
+	}
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	for _, dashboard := range opts.Dashboards {
+		name, template, found := strings.Cut(dashboard, "=")
+		if !found {
+			name, template = "dashboard", dashboard
+		}
+		pterm.Info.Printf("%s: %s\n", name, dashboardURL(template, line))
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/pflag"
+)
+
+// Environment variable silencing the warnings of deprecated flags, for scripts not migrated yet
+const silenceDeprecationsEnv = "KLOG_NO_DEPRECATION_WARNINGS"
+
+// Flags of earlier versions still accepted under their new name, with the syntax to use instead
+var deprecatedFlags = map[string]struct{ name, hint string }{
+	"tailLines":     {"tail", "--tail"},
+	"lastContainer": {"previous", "--previous"},
+	"allPods":       {"all-pods", "--all-pods"},
+	"sinceTime":     {"since-hours", "--since with a unit, e.g. --since 24h"},
+}
+
+// Other names of flags, accepted without warning
+var flagAliases = map[string]string{
+	"level": "min-level",
+}
+
+// Function to parse the old spelling of a renamed flag as the new one
+func normalizeFlagName(f *pflag.FlagSet, name string) pflag.NormalizedName {
+	if deprecated, ok := deprecatedFlags[name]; ok {
+		return pflag.NormalizedName(deprecated.name)
+	}
+	if alias, ok := flagAliases[name]; ok {
+		return pflag.NormalizedName(alias)
+	}
+	return pflag.NormalizedName(name)
+}
+
+// Function to warn once about each deprecated flag of the command line, unless silenced
+func warnDeprecatedFlags(args []string) {
+	if os.Getenv(silenceDeprecationsEnv) != "" {
+		return
+	}
+
+	warned := make(map[string]bool)
+	for _, arg := range args {
+		// Arguments after -- are not flags
+		if arg == "--" {
+			return
+		}
+		name, _, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		deprecated, ok := deprecatedFlags[name]
+		if !ok || !strings.HasPrefix(arg, "--") || warned[name] {
+			continue
+		}
+		warned[name] = true
+		pterm.Warning.Printf("--%s is deprecated, use %s (%s=1 silences this warning)\n", name, deprecated.hint, silenceDeprecationsEnv)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var dumpCmd = &cobra.Command{
+	Use:     "dump <pod-name> <dir>",
+	Short:   "Save the logs and events of a pod, or of every matched pod with -a, into a directory.",
+	Example: "  klog dump my-api ./incident -a --with-manifest",
+	Args:    cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := flags
+		opts.Dump = args[1]
+		dump(args[0], opts)
+	},
+}
+
+func init() {
+	dumpCmd.Flags().BoolVar(&flags.WithManifest, "with-manifest", false, "Also save the pod YAML next to its logs")
+}
+
+// Function to dump the logs of the selected pods into opts.Dump
+func dump(pattern string, opts Options) {
+	ctx := context.Background()
+	clientset := newClientset(opts)
+	pods, containers := selectedPods(ctx, clientset, pattern, opts)
+	checkFetchBudget(ctx, clientset, pods, containers, true, opts)
+
+	// The manifest and events are saved once per pod, whatever its number of containers
+	saved := make(map[string]bool)
+	for i := range pods {
+		podLogOptions := buildLogOptions(&pods[i], containers[i], opts)
+		podLogOptions.Follow = false
+		if err := dumpPod(ctx, clientset, &pods[i], podLogOptions, opts); err != nil {
+			pterm.Error.Printf("Error dumping logs: %v\n", err)
+			os.Exit(1)
+		}
+
+		key := pods[i].Namespace + "/" + pods[i].Name
+		if saved[key] {
+			continue
+		}
+		saved[key] = true
+		if err := dumpPodFiles(ctx, clientset, &pods[i], logsSince(podLogOptions), opts); err != nil {
+			pterm.Error.Printf("Error dumping pod: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// Function to get the dump directory of a pod
+func podDumpDir(dir string, pod *v1.Pod) string {
+	return filepath.Join(dir, pod.Namespace+"_"+pod.Name)
+}
+
+// Function to write the logs of a container into the dump directory
+func dumpPod(ctx context.Context, clientset *kubernetes.Clientset, pod *v1.Pod, podLogOptions *v1.PodLogOptions, opts Options) error {
+	podDir := podDumpDir(opts.Dump, pod)
+	if err := os.MkdirAll(podDir, 0o755); err != nil {
+		return err
+	}
+
+	stream, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, podLogOptions).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	logPath := filepath.Join(podDir, podLogOptions.Container+".log")
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	if _, err := io.Copy(logFile, stream); err != nil {
+		return err
+	}
+	pterm.Success.Printf("Logs of container '%s' saved to %s\n", podLogOptions.Container, logPath)
+	return nil
+}
+
+// Function to write the events of a pod, and optionally its YAML, into the dump directory. Missing
+// events don't make the logs already saved useless.
+func dumpPodFiles(ctx context.Context, clientset *kubernetes.Clientset, pod *v1.Pod, since *metav1.Time, opts Options) error {
+	podDir := podDumpDir(opts.Dump, pod)
+	if opts.WithManifest {
+		manifestPath := filepath.Join(podDir, "pod.yaml")
+		if err := writePodManifest(pod, manifestPath); err != nil {
+			return err
+		}
+		pterm.Success.Printf("Manifest of pod '%s' saved to %s\n", pod.Name, manifestPath)
+	}
+
+	eventsPath := filepath.Join(podDir, "events.txt")
+	if err := writePodEvents(ctx, clientset, pod, since, eventsPath); err != nil {
+		pterm.Warning.Printf("Error saving events of pod '%s': %v\n", pod.Name, err)
+		return nil
+	}
+	pterm.Success.Printf("Events of pod '%s' saved to %s\n", pod.Name, eventsPath)
+	return nil
+}
+
+// Function to save the events of a pod and its owners since the start of the dump window
+func writePodEvents(ctx context.Context, clientset *kubernetes.Clientset, pod *v1.Pod, since *metav1.Time, path string) error {
+	objects := []string{pod.Name}
+	for _, owner := range pod.OwnerReferences {
+		objects = append(objects, owner.Name)
+	}
+
+	var events []v1.Event
+	for _, name := range objects {
+		selector := fields.OneTermEqualSelector("involvedObject.name", name).String()
+		list, err := clientset.CoreV1().Events(pod.Namespace).List(ctx, metav1.ListOptions{FieldSelector: selector})
+		if err != nil {
+			return err
+		}
+		for _, event := range list.Items {
+			if since != nil && eventTime(event).Before(since.Time) {
+				continue
+			}
+			events = append(events, event)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return eventTime(events[i]).Before(eventTime(events[j]))
+	})
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := tabwriter.NewWriter(file, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "LAST SEEN\tTYPE\tREASON\tOBJECT\tCOUNT\tMESSAGE")
+	for _, event := range events {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s/%s\t%d\t%s\n",
+			eventTime(event).Format(time.RFC3339), event.Type, event.Reason,
+			event.InvolvedObject.Kind, event.InvolvedObject.Name, event.Count, event.Message)
+	}
+	return writer.Flush()
+}
+
+// Function to get the most recent time an event was observed
+func eventTime(event v1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case event.Series != nil:
+		return event.Series.LastObservedTime.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
+
+// Function to save the spec and status of a pod as it was at capture time
+func writePodManifest(pod *v1.Pod, path string) error {
+	snapshot := pod.DeepCopy()
+	snapshot.APIVersion = "v1"
+	snapshot.Kind = "Pod"
+	snapshot.ManagedFields = nil
+
+	data, err := yaml.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
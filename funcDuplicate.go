@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// How long a pod counts as affected by a message after it logged it
+const duplicateWindow = time.Minute
+
+// Variable parts of messages replaced before fingerprinting
+var normalizePatterns = []struct {
+	re          *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`), "<time>"},
+	{regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`), "<uuid>"},
+	{regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}(:\d+)?\b`), "<ip>"},
+	{regexp.MustCompile(`\b(0x)?[0-9a-fA-F]*\d[0-9a-fA-F]*\b`), "<n>"},
+}
+
+// Track which pods logged each error fingerprint
+type duplicateTracker struct {
+	mu        sync.Mutex
+	totalPods int
+	seen      map[uint64]map[string]time.Time
+	reported  map[uint64]bool
+	pruned    time.Time
+}
+
+var duplicates = &duplicateTracker{
+	seen:     make(map[uint64]map[string]time.Time),
+	reported: make(map[uint64]bool),
+}
+
+// Message templates seen during the session, to flag novel errors
+type templateTracker struct {
+	mu   sync.Mutex
+	seen map[uint64]bool
+}
+
+var templates = &templateTracker{seen: make(map[uint64]bool)}
+
+// Function to record the template of a line and tell whether it never appeared before
+func (t *templateTracker) novel(line string) bool {
+	key := fingerprint(line)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.seen[key] {
+		return false
+	}
+	t.seen[key] = true
+	return true
+}
+
+// Function to replace the variable parts of a message (ids, numbers, times) with placeholders
+func normalizeMessage(line string) string {
+	var logEntry map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &logEntry); err == nil {
+		for _, key := range []string{"msg", "message"} {
+			if msg, ok := logEntry[key].(string); ok {
+				line = msg
+				break
+			}
+		}
+	} else if pairs, ok := parseLogfmt(line); ok {
+		for _, pair := range pairs {
+			if logfmtMessageKeys[pair.key] {
+				line = pair.value
+				break
+			}
+		}
+	}
+
+	for _, pattern := range normalizePatterns {
+		line = pattern.re.ReplaceAllString(line, pattern.replacement)
+	}
+	return line
+}
+
+// Function to fingerprint a message independently of its variable parts
+func fingerprint(line string) uint64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(normalizeMessage(line)))
+	return hash.Sum64()
+}
+
+// Function to count the pods joining the session
+func (d *duplicateTracker) addPods(count int) {
+	d.mu.Lock()
+	d.totalPods += count
+	d.mu.Unlock()
+}
+
+// Function to forget the messages no pod logged within the window, with d.mu held, so that a
+// message seen again later is reported again
+func (d *duplicateTracker) prune(now time.Time) {
+	if now.Sub(d.pruned) < duplicateWindow {
+		return
+	}
+	d.pruned = now
+	for key, pods := range d.seen {
+		for name, last := range pods {
+			if now.Sub(last) > duplicateWindow {
+				delete(pods, name)
+			}
+		}
+		if len(pods) == 0 {
+			delete(d.seen, key)
+			delete(d.reported, key)
+		}
+	}
+}
+
+// Function to record a line of a pod and return the tag to display when other pods logged it too
+func (d *duplicateTracker) observe(pod string, line string, level string, opts Options) string {
+	key := fingerprint(line)
+	now := time.Now()
+
+	d.mu.Lock()
+	d.prune(now)
+	pods, ok := d.seen[key]
+	if !ok {
+		pods = make(map[string]time.Time)
+		d.seen[key] = pods
+	}
+	pods[pod] = now
+
+	count := 0
+	for name, last := range pods {
+		if now.Sub(last) > duplicateWindow {
+			delete(pods, name)
+			continue
+		}
+		count++
+	}
+
+	totalPods := d.totalPods
+	if count < 2 {
+		d.mu.Unlock()
+		return ""
+	}
+
+	// Report once when the message reaches a majority of the pods
+	report := count*2 >= totalPods && !d.reported[key]
+	if report {
+		d.reported[key] = true
+	}
+	d.mu.Unlock()
+
+	if report {
+		// The warning must not interleave with the lines of concurrent streams
+		outputMu.Lock()
+		if opts.MetadataOnly {
+			pterm.Warning.Printf("Same %s seen in %d/%d pods\n", level, count, totalPods)
+		} else {
+			pterm.Warning.Printf("Same %s seen in %d/%d pods: %s\n", level, count, totalPods, normalizeMessage(line))
+		}
+		outputMu.Unlock()
+	}
+	return pterm.FgDarkGray.Sprintf(" [seen in %d/%d pods]", count, totalPods)
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestNormalizeMessage(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"connection refused", "connection refused"},
+		{"timeout after 30 seconds on request 12345", "timeout after <n> seconds on request <n>"},
+		{"user 3f2b8c1e-9a4d-4e6f-8b2a-1c3d5e7f9a0b not found", "user <uuid> not found"},
+		{"dial tcp 10.0.3.17:5432: connect: connection refused", "dial tcp <ip>: connect: connection refused"},
+		{"started at 2024-03-01T10:00:00.123Z", "started at <time>"},
+		{"started at 2024-03-01 10:00:00+02:00", "started at <time>"},
+		{"pointer 0x7ffd5e8a", "pointer <n>"},
+		{`{"level":"error","msg":"order 42 failed","order":42}`, "order <n> failed"},
+		{`{"level":"error","message":"payment 7 declined"}`, "payment <n> declined"},
+		{`{"level":"error","code":500}`, `{"level":"error","code":<n>}`},
+		{`level=error msg="order 42 failed" order=42`, "order <n> failed"},
+		{`level=error err="disk full"`, `level=error err="disk full"`},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeMessage(tt.line); got != tt.want {
+			t.Errorf("normalizeMessage(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
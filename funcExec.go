@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+
+	"github.com/pterm/pterm"
+)
+
+// Function to run --with-exec in the followed containers every --exec-interval, and once at the start
+func startExecs(ctx context.Context, clientset *kubernetes.Clientset, sources []logSource, opts Options) {
+	command := strings.Fields(opts.WithExec)
+	if len(command) == 0 {
+		return
+	}
+	if opts.MetadataOnly {
+		pterm.Warning.Println("--with-exec is not run with --metadata-only, its output would be displayed")
+		return
+	}
+	if opts.ExecInterval <= 0 {
+		pterm.Error.Println("--exec-interval must be positive")
+		return
+	}
+
+	config := loadKubeConfig(opts)
+	for _, src := range sources {
+		go func() {
+			ticker := time.NewTicker(opts.ExecInterval)
+			defer ticker.Stop()
+			for {
+				runExec(ctx, clientset, config, src, command, opts.ExecInterval)
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+}
+
+// Function to run a command in a container and print its output as a block of the stream
+func runExec(ctx context.Context, clientset *kubernetes.Clientset, config *rest.Config, src logSource, command []string, timeout time.Duration) {
+	// A stopped container would fail the exec
+	if !containerRunning(ctx, clientset, src) {
+		return
+	}
+
+	request := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(src.Namespace).Name(src.Pod).SubResource("exec").
+		VersionedParams(&v1.PodExecOptions{Container: src.Container, Command: command, Stdout: true, Stderr: true}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(config, "POST", request.URL())
+	if err != nil {
+		pterm.Warning.Printf("Unable to exec in container '%s' of pod '%s': %v\n", src.Container, src.Pod, err)
+		return
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var out bytes.Buffer
+	started := time.Now()
+	err = executor.StreamWithContext(execCtx, remotecommand.StreamOptions{Stdout: &out, Stderr: &out})
+	if ctx.Err() != nil {
+		return
+	}
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	fmt.Fprintln(markerOutput, pterm.FgLightBlue.Sprintf("──── exec '%s' in %s/%s at %s ────", strings.Join(command, " "), src.Pod, src.Container, started.Format(timestampFormat)))
+	fmt.Fprint(markerOutput, out.String())
+	if out.Len() > 0 && !bytes.HasSuffix(out.Bytes(), []byte("\n")) {
+		fmt.Fprintln(markerOutput)
+	}
+	if err != nil {
+		fmt.Fprintln(markerOutput, pterm.FgLightBlue.Sprintf("──── exec failed: %v ────", err))
+		return
+	}
+	fmt.Fprintln(markerOutput, pterm.FgLightBlue.Sprintf("──── end of exec '%s' (%s) ────", strings.Join(command, " "), time.Since(started).Round(time.Millisecond)))
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// Addresses and cluster-internal host names removed from every sanitized export
+var sanitizePatterns = []struct {
+	re          *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}(:\d+)?\b`), "<ip>"},
+	{regexp.MustCompile(`\b([0-9a-fA-F]{1,4}:){7}[0-9a-fA-F]{1,4}\b`), "<ip>"},
+	{regexp.MustCompile(`\b[a-zA-Z0-9-]+(\.[a-zA-Z0-9-]+)*\.(svc(\.cluster\.local)?|cluster\.local|internal|local|lan|corp)\b`), "<host>"},
+}
+
+// Line kept for the export, ordered by its time
+type exportLine struct {
+	time time.Time
+	src  logSource
+	line string
+}
+
+// Function to redact a line with the built-in patterns and the redact rules of the config
+func sanitizeLine(line string) string {
+	for _, r := range activeRules.redact {
+		replacement := r.Replacement
+		if replacement == "" {
+			replacement = "<redacted>"
+		}
+		line = r.re.ReplaceAllString(line, replacement)
+	}
+	for _, pattern := range sanitizePatterns {
+		line = pattern.re.ReplaceAllString(line, pattern.replacement)
+	}
+	return line
+}
+
+// Function to write the logs of the selected pods, redacted, without debug or muted lines, in time order
+func exportSanitized(pattern string, opts Options) {
+	ctx := context.Background()
+	clientset := newClientset(opts)
+	pods, containers := selectedPods(ctx, clientset, pattern, opts)
+	checkFetchBudget(ctx, clientset, pods, containers, true, opts)
+
+	var lines []exportLine
+	dropped := 0
+	for i, pod := range pods {
+		podLogOptions := buildLogOptions(&pods[i], containers[i], opts)
+		podLogOptions.Follow = false
+		podLogOptions.Timestamps = true
+
+		stream, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, podLogOptions).Stream(ctx)
+		if err != nil {
+			pterm.Error.Printf("Error fetching logs of pod '%s': %v\n", pod.Name, err)
+			os.Exit(1)
+		}
+
+		src := logSource{Namespace: pod.Namespace, Pod: pod.Name, Container: containers[i]}
+		scanner := newLineScanner(stream)
+		for scanner.Scan() {
+			timestamp, line, _ := strings.Cut(scanner.Text(), " ")
+			t, _ := time.Parse(time.RFC3339Nano, timestamp)
+
+			line, unwrapped := activeRules.unwrap(line)
+			if appTime, ok := activeRules.eventTime(line, unwrapped); ok {
+				t = appTime
+			}
+
+			level, _, _ := activeRules.classify(line)
+			if level == "debug" || activeRules.muted(line) {
+				dropped++
+				continue
+			}
+			lines = append(lines, exportLine{time: t, src: src, line: sanitizeLine(line)})
+		}
+		stream.Close()
+		if err := scanner.Err(); err != nil {
+			pterm.Error.Printf("Error reading logs of pod '%s': %v\n", pod.Name, err)
+			os.Exit(1)
+		}
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].time.Before(lines[j].time) })
+
+	var out strings.Builder
+	for _, line := range lines {
+		fmt.Fprintf(&out, "%s %s/%s %s\n", line.time.UTC().Format(time.RFC3339Nano), line.src.Pod, line.src.Container, line.line)
+	}
+	if err := os.WriteFile(opts.ExportSanitized, []byte(out.String()), 0o644); err != nil {
+		pterm.Error.Printf("Error writing export: %v\n", err)
+		os.Exit(1)
+	}
+	pterm.Success.Printf("%d lines of %d containers exported to %s (%d debug or muted lines left out)\n", len(lines), len(pods), opts.ExportSanitized, dropped)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Function to replace a JSON line by the --fields it has, as key=value pairs in the order given,
+// a field being a dotted path into nested objects
+func projectFields(fields map[string]interface{}, names []string) (string, bool) {
+	if fields == nil || len(names) == 0 {
+		return "", false
+	}
+
+	var pairs []string
+	for _, name := range names {
+		value := lookupField(fields, name)
+		if value == nil {
+			continue
+		}
+		pairs = append(pairs, name+"="+fieldText(value))
+	}
+	return strings.Join(pairs, " "), len(pairs) > 0
+}
+
+// Function to get a field of a parsed line by its flat key, like log.level, or else by its dotted path
+func lookupField(fields map[string]interface{}, name string) interface{} {
+	if value, ok := fields[name]; ok {
+		return value
+	}
+	var value interface{} = fields
+	for _, key := range strings.Split(name, ".") {
+		object, _ := value.(map[string]interface{})
+		value = object[key]
+	}
+	return value
+}
+
+// Function to write a JSON value as a logfmt value, quoted when it has spaces
+func fieldText(value interface{}) string {
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = v
+	case float64:
+		text = strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		data, _ := json.Marshal(v)
+		text = string(data)
+	default:
+		text = fmt.Sprint(v)
+	}
+	if text == "" || strings.ContainsAny(text, " \t\"=") {
+		return strconv.Quote(text)
+	}
+	return text
+}
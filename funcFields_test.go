@@ -0,0 +1,7 @@
+package main
+
+import "testing"
+
+func TestFieldsGolden(t *testing.T) {
+	checkFlagGolden(t, "fields", Options{Fields: []string{"msg", "trace_id", "upstream.host", "status"}}, ruleFile{})
+}
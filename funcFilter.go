@@ -0,0 +1,115 @@
+package main
+
+import (
+	"regexp"
+	"sync/atomic"
+
+	"github.com/pterm/pterm"
+)
+
+// Metadata filters selecting which streams are displayed
+type metadataFilter struct {
+	pods       *regexp.Regexp
+	containers *regexp.Regexp
+	nodes      *regexp.Regexp
+}
+
+var activeFilter atomic.Pointer[metadataFilter]
+
+// Lines dropped with --exclude
+var excludePatterns []*regexp.Regexp
+
+// Function to compile the --only-* flags
+func initMetadataFilter(opts Options) error {
+	filter := &metadataFilter{}
+	for _, option := range []struct {
+		pattern string
+		target  **regexp.Regexp
+	}{
+		{opts.OnlyPods, &filter.pods},
+		{opts.OnlyContainers, &filter.containers},
+		{opts.OnlyNodes, &filter.nodes},
+	} {
+		if option.pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(option.pattern)
+		if err != nil {
+			return err
+		}
+		*option.target = re
+	}
+	activeFilter.Store(filter)
+	return nil
+}
+
+// Function to compile the --exclude regexes
+func initExcludes(opts Options) error {
+	excludePatterns = nil
+	for _, pattern := range opts.Exclude {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		excludePatterns = append(excludePatterns, re)
+	}
+	return nil
+}
+
+// Function to check whether a line matches one of the --exclude regexes
+func excluded(line string) bool {
+	for _, re := range excludePatterns {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *metadataFilter) allows(src logSource) bool {
+	return (f.pods == nil || f.pods.MatchString(src.Pod)) &&
+		(f.containers == nil || f.containers.MatchString(src.Container)) &&
+		(f.nodes == nil || f.nodes.MatchString(src.Node))
+}
+
+// Function to check whether lines of a source are displayed
+func sourceAllowed(src logSource) bool {
+	filter := activeFilter.Load()
+	return filter == nil || filter.allows(src)
+}
+
+// Function to change one metadata filter of the running session, an empty pattern removes it
+func setMetadataFilter(kind string, pattern string) {
+	var re *regexp.Regexp
+	if pattern != "" {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			pterm.Error.Printf("Invalid pattern: %v\n", err)
+			return
+		}
+	}
+
+	filter := metadataFilter{}
+	if current := activeFilter.Load(); current != nil {
+		filter = *current
+	}
+
+	switch kind {
+	case "pods":
+		filter.pods = re
+	case "containers":
+		filter.containers = re
+	case "nodes":
+		filter.nodes = re
+	default:
+		pterm.Warning.Printf("Unknown filter '%s', use pods, containers or nodes\n", kind)
+		return
+	}
+	activeFilter.Store(&filter)
+
+	if re == nil {
+		pterm.Info.Printf("Showing all %s\n", kind)
+	} else {
+		pterm.Info.Printf("Showing only %s matching '%s'\n", kind, pattern)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// Counters reported when an idle session is closed
+type sessionCounters struct {
+	mu     sync.Mutex
+	start  time.Time
+	lines  int
+	levels map[string]int
+}
+
+var (
+	session     = &sessionCounters{start: time.Now(), levels: make(map[string]int)}
+	idleTimer   *time.Timer
+	idleTimeout time.Duration
+)
+
+// Steps completing the session, registered by the features holding lines back or writing files
+var (
+	cleanupsMu sync.Mutex
+	cleanups   []func()
+	finished   sync.Once
+)
+
+// Function to register a step completing the session, run whether the streams ended or the session
+// is closed, in reverse order of registration like defers
+func onSessionEnd(cleanup func()) {
+	cleanupsMu.Lock()
+	cleanups = append(cleanups, cleanup)
+	cleanupsMu.Unlock()
+}
+
+// Function to complete the session once: the lines held back are displayed, then the files completed
+func finishSession() {
+	finished.Do(func() {
+		cleanupsMu.Lock()
+		steps := cleanups
+		cleanupsMu.Unlock()
+		for i := len(steps) - 1; i >= 0; i-- {
+			steps[i]()
+		}
+	})
+}
+
+func (s *sessionCounters) count(level string) {
+	s.mu.Lock()
+	s.lines++
+	s.levels[level]++
+	s.mu.Unlock()
+}
+
+// Function to close the session once no line was received for --exit-idle
+func startIdleTimer(timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+
+	idleTimeout = timeout
+	idleTimer = time.AfterFunc(idleTimeout, func() {
+		closeSession(fmt.Sprintf("No log line for %s", idleTimeout))
+	})
+}
+
+// Function to close the session after --max-session, e.g. before the credentials of the streams expire
+func startSessionTimer(maxSession time.Duration) {
+	if maxSession <= 0 {
+		return
+	}
+	time.AfterFunc(maxSession, func() {
+		closeSession(fmt.Sprintf("Maximum session duration of %s reached", maxSession))
+	})
+}
+
+// Function to complete the session before exiting on Ctrl+C or SIGTERM, the only signal handler of klog
+func startInterruptHandler() {
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupted
+		finishSession()
+		os.Exit(1)
+	}()
+}
+
+// Function to stop every stream and exit with the counters of the session
+func closeSession(reason string) {
+	finishSession()
+
+	// No line is displayed after the counters
+	outputMu.Lock()
+	session.mu.Lock()
+	pterm.Info.Printf("%s, closing session after %s (%d lines: %d error, %d warning)\n",
+		reason, time.Since(session.start).Round(time.Second), session.lines,
+		session.levels["error"], session.levels["warning"]+session.levels["panic"])
+	os.Exit(0)
+}
+
+// Function to restart the idle countdown when a line is received
+func resetIdleTimer() {
+	if idleTimer != nil {
+		idleTimer.Reset(idleTimeout)
+	}
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/pterm/pterm"
+)
+
+// Delays between the attempts to resume a dropped stream, doubled after each failed attempt
+const (
+	reconnectMinDelay = time.Second
+	reconnectMaxDelay = 30 * time.Second
+)
+
+// Pods younger than this are streamed from their creation
+const freshPodAge = 2 * time.Minute
+
+// Longest log line read, bufio stops at 64KiB by default
+const maxLineSize = 1024 * 1024
+
+func selectContainer(containers []v1.Container) string {
+	// If only one container is available, return its name directly
+	if len(containers) == 1 {
+		return containers[0].Name
+	}
+
+	// Use container names in interactive interface
+	selectorContainer := pterm.DefaultInteractiveSelect.WithDefaultText("Select a container")
+	selectorContainer.MaxHeight = 10
+
+	// Create a slice of strings to store container names
+	containerNames := make([]string, len(containers))
+	for i, container := range containers {
+		containerNames[i] = container.Name
+	}
+
+	selectedOption, _ := selectorContainer.WithOptions(containerNames).Show()
+
+	fmt.Print("\033[F\033[K\033[F\033[K") // Remove last 2 lines
+	return selectedOption
+}
+
+func selectPod(matchedPods []v1.Pod, withNamespace bool) v1.Pod {
+	if len(matchedPods) == 1 {
+		return matchedPods[0]
+	}
+
+	// Pods of different namespaces may have the same name
+	seen := make(map[string]bool)
+	for _, pod := range matchedPods {
+		withNamespace = withNamespace || seen[pod.Name]
+		seen[pod.Name] = true
+	}
+
+	podNames := make([]string, len(matchedPods))
+	for i, pod := range matchedPods {
+		podNames[i] = pod.Name
+		if withNamespace {
+			podNames[i] = pod.Namespace + "/" + pod.Name
+		}
+	}
+
+	selectorPod := pterm.DefaultInteractiveSelect.WithDefaultText("Select a pod")
+	selectorPod.MaxHeight = 10
+	selectedOption, _ := selectorPod.WithOptions(podNames).Show() // The Show() method displays the options and waits for the user's input
+
+	fmt.Print("\033[F\033[K\033[F\033[K") // Remove last 2 lines
+	for i, name := range podNames {
+		if name == selectedOption {
+			return matchedPods[i]
+		}
+	}
+	return matchedPods[0]
+}
+
+// Function to keep the pods whose name matches the regex
+func matchPods(pods []v1.Pod, pattern string) []v1.Pod {
+	var matchedPods []v1.Pod
+	for _, p := range pods {
+		if matched, _ := regexp.MatchString(pattern, p.Name); matched {
+			matchedPods = append(matchedPods, p)
+		}
+	}
+	return matchedPods
+}
+
+// Function to tell whether lines are requested with their kubelet timestamp, displayed or only used to order them
+func withTimestamps(opts Options) bool {
+	return opts.Timestamp || opts.HideTimestamps || opts.Sort
+}
+
+// Function to leave out the pods matching one of the --exclude-pod regexes
+func excludePods(pods []v1.Pod, patterns []string) []v1.Pod {
+	var kept []v1.Pod
+	for _, p := range pods {
+		excluded := false
+		for _, pattern := range patterns {
+			if matched, _ := regexp.MatchString(pattern, p.Name); matched {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// Function to construct the PodLogOptions of a container from the options
+func buildLogOptions(pod *v1.Pod, container string, opts Options) *v1.PodLogOptions {
+	podLogOptions := &v1.PodLogOptions{
+		Container:  container,
+		Timestamps: withTimestamps(opts), // Parse timestamps
+		Follow:     true,                 // Enable log streaming by default
+		Previous:   opts.LastContainer,   // Display logs of the previous container
+	}
+
+	if opts.Since > 0 {
+		sinceSeconds := int64(opts.Since.Seconds())
+		podLogOptions.SinceSeconds = &sinceSeconds
+	}
+	if start, ok := sinceTimestamp(opts); ok {
+		sinceTime := metav1.NewTime(start)
+		podLogOptions.SinceTime = &sinceTime
+	}
+
+	if opts.TailLines > 0 {
+		tailLines := int64(opts.TailLines)
+		podLogOptions.TailLines = &tailLines
+	}
+	if opts.LimitBytes > 0 {
+		podLogOptions.LimitBytes = &opts.LimitBytes
+	}
+
+	// Tail or since limits could cut the startup lines of a pod that just started, an exact start is kept
+	if !opts.LastContainer && opts.SinceTimestamp == "" && time.Since(pod.CreationTimestamp.Time) < freshPodAge {
+		podLogOptions.SinceTime = &pod.CreationTimestamp
+		podLogOptions.SinceSeconds = nil
+		podLogOptions.TailLines = nil
+	}
+	return podLogOptions
+}
+
+// Function to get the --since-time start, checked when the command starts
+func sinceTimestamp(opts Options) (time.Time, bool) {
+	start, err := time.Parse(time.RFC3339, opts.SinceTimestamp)
+	return start, opts.SinceTimestamp != "" && err == nil
+}
+
+// Function to get the start requested by log options, nil for the whole log
+func logsSince(podLogOptions *v1.PodLogOptions) *metav1.Time {
+	if podLogOptions.SinceTime != nil {
+		return podLogOptions.SinceTime
+	}
+	if podLogOptions.SinceSeconds != nil {
+		since := metav1.NewTime(time.Now().Add(-time.Duration(*podLogOptions.SinceSeconds) * time.Second))
+		return &since
+	}
+	return nil
+}
+
+// Function to stream the logs of a container and print each line
+func streamLogs(ctx context.Context, clientset *kubernetes.Clientset, src logSource, podLogOptions *v1.PodLogOptions, opts Options) error {
+	backfillFromLoki(ctx, clientset, src, podLogOptions, opts)
+
+	var shutdown *shutdownCapture
+	if opts.CaptureTermination && podLogOptions.Follow {
+		shutdown = watchShutdown(ctx, clientset, src)
+	}
+
+	// Time of the last line, and of the last one printed before the stream was resumed
+	var last, resumed time.Time
+	delay := reconnectMinDelay
+	for connected := false; ; connected = true {
+		// Enable log streaming
+		stream, err := clientset.CoreV1().Pods(src.Namespace).GetLogs(src.Pod, podLogOptions).Stream(ctx)
+		if apierrors.IsUnauthorized(err) {
+			// Tokens of exec credential plugins expire, load the kubeconfig again to run the plugin
+			pterm.Warning.Printf("Credentials rejected for pod '%s', refreshing them\n", src.Pod)
+			clientset = newClientset(opts)
+			stream, err = clientset.CoreV1().Pods(src.Namespace).GetLogs(src.Pod, podLogOptions).Stream(ctx)
+		}
+		if err != nil && (!connected || ctx.Err() != nil) {
+			return fmt.Errorf("starting log streaming: %w", err)
+		}
+		if apierrors.IsNotFound(err) {
+			break
+		}
+		if err != nil {
+			// The API server may be restarting
+			pterm.Warning.Printf("Unable to reconnect to container '%s' in pod '%s', retrying in %s: %v\n", src.Container, src.Pod, delay, err)
+			if !sleepContext(ctx, delay) {
+				break
+			}
+			delay = min(2*delay, reconnectMaxDelay)
+			continue
+		}
+
+		scanner := newLineScanner(stream)
+		for scanner.Scan() {
+			line := scanner.Text()
+			lineTime, ok := kubeletTime(line, opts)
+			if ok && !lineTime.After(resumed) {
+				// Already printed before the stream was resumed
+				continue
+			}
+			if !ok {
+				lineTime = time.Now()
+			}
+			last, resumed = lineTime, time.Time{}
+			delay = reconnectMinDelay
+
+			// Use function to highlight keyword
+			printLogLine(src, line, opts)
+		}
+		stream.Close()
+
+		err = scanner.Err()
+		if err != nil && (!podLogOptions.Follow || ctx.Err() != nil || errors.Is(err, bufio.ErrTooLong)) {
+			return fmt.Errorf("reading logs: %w", err)
+		}
+		if err == nil && (!podLogOptions.Follow || podLogOptions.Previous || ctx.Err() != nil) {
+			break
+		}
+		// Resuming would pull the limit again
+		if err == nil && podLogOptions.LimitBytes != nil {
+			pterm.Info.Printf("Stream of container '%s' in pod '%s' stopped after --limit-bytes %d\n", src.Container, src.Pod, *podLogOptions.LimitBytes)
+			return nil
+		}
+		// A stopped container is followed again once restarted, from the start of its new instance
+		if err == nil && !containerRunning(ctx, clientset, src) {
+			if !waitForRestart(ctx, clientset, src) {
+				break
+			}
+			podLogOptions.SinceTime, podLogOptions.SinceSeconds, podLogOptions.TailLines = nil, nil, nil
+			resumed, delay = time.Time{}, reconnectMinDelay
+			continue
+		}
+		// The connection dropped while following, resume after the last line
+		if err == nil {
+			// API servers also close streams cleanly, e.g. on idle timeouts, while the container runs
+			pterm.Warning.Printf("Stream of container '%s' in pod '%s' closed by the API server, resuming in %s\n", src.Container, src.Pod, delay)
+		} else {
+			pterm.Warning.Printf("Stream of container '%s' in pod '%s' interrupted (%v), resuming in %s\n", src.Container, src.Pod, err, delay)
+		}
+		if !sleepContext(ctx, delay) {
+			break
+		}
+		delay = min(2*delay, reconnectMaxDelay)
+		if !last.IsZero() {
+			resumed = last
+			resumeTime := metav1.NewTime(last)
+			podLogOptions.SinceTime = &resumeTime
+			podLogOptions.SinceSeconds = nil
+			podLogOptions.TailLines = nil
+		}
+	}
+
+	if shutdown != nil && shutdown.report(src) {
+		return nil
+	}
+	if podLogOptions.Follow && ctx.Err() == nil {
+		printStreamEnd(ctx, clientset, src)
+	}
+	return nil
+}
+
+// Function to tell whether a followed container still runs, in doubt when its pod can't be read
+func containerRunning(ctx context.Context, clientset *kubernetes.Clientset, src logSource) bool {
+	pod, err := clientset.CoreV1().Pods(src.Namespace).Get(ctx, src.Pod, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	status := containerStatus(pod, src.Container)
+	return status != nil && status.State.Running != nil
+}
+
+// Function to wait for a duration, false when the context ended first
+func sleepContext(ctx context.Context, delay time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(delay):
+		return true
+	}
+}
+
+// Function to get the kubelet timestamp of a line requested with timestamps
+func kubeletTime(line string, opts Options) (time.Time, bool) {
+	if !withTimestamps(opts) {
+		return time.Time{}, false
+	}
+	timestamp, _, _ := strings.Cut(line, " ")
+	t, err := time.Parse(time.RFC3339Nano, timestamp)
+	return t, err == nil
+}
+
+// Function to read log lines, including the long ones of JSON payloads
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return scanner
+}
+
+// Function to get the kubeconfig client configuration, loaded like kubectl from --kubeconfig,
+// else the $KUBECONFIG paths merged, else ~/.kube/config, and switched to the --context one if set
+func kubeClientConfig(opts Options) clientcmd.ClientConfig {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = opts.Kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: opts.Context}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+}
+
+func loadKubeConfig(opts Options) *rest.Config {
+	config, err := kubeClientConfig(opts).ClientConfig()
+	if err != nil {
+		pterm.Error.Printf("Error loading Kubernetes configuration: %v\n", err)
+		os.Exit(2)
+	}
+	return config
+}
+
+// Function to get the name of the kubeconfig context in use
+func currentContext(opts Options) string {
+	if opts.Context != "" {
+		return opts.Context
+	}
+	rawConfig, err := kubeClientConfig(opts).RawConfig()
+	if err != nil {
+		return ""
+	}
+	return rawConfig.CurrentContext
+}
+
+// Function to build the kubectl logs command equivalent to the current selection
+func kubectlCommand(namespace string, pod string, container string, opts Options) string {
+	args := []string{"kubectl", "logs", pod, "-n", namespace, "-c", container, "-f"}
+	if container == "" {
+		args = []string{"kubectl", "logs", pod, "-n", namespace, "--all-containers", "-f"}
+	}
+
+	if withTimestamps(opts) {
+		args = append(args, "--timestamps")
+	}
+	if opts.LastContainer {
+		args = append(args, "--previous")
+	}
+	if opts.Since > 0 {
+		args = append(args, "--since="+opts.Since.String())
+	}
+	if opts.SinceTimestamp != "" {
+		args = append(args, "--since-time="+opts.SinceTimestamp)
+	}
+	if opts.TailLines > 0 {
+		args = append(args, fmt.Sprintf("--tail=%d", opts.TailLines))
+	}
+	if opts.LimitBytes > 0 {
+		args = append(args, fmt.Sprintf("--limit-bytes=%d", opts.LimitBytes))
+	}
+
+	for i, arg := range args {
+		args[i] = shellQuote(arg)
+	}
+	return strings.Join(args, " ")
+}
+
+// Characters safe unquoted in a POSIX shell
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_./:=@%+,-]+$`)
+
+// Function to quote an argument for a POSIX shell when it needs it, e.g. a --since-time with spaces
+func shellQuote(arg string) string {
+	if shellSafe.MatchString(arg) {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
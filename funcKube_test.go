@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestKubectlCommand(t *testing.T) {
+	tests := []struct {
+		container string
+		opts      Options
+		want      string
+	}{
+		{"api", Options{}, "kubectl logs orders-7d4b9c-x2k8p -n shop -c api -f"},
+		{"", Options{TailLines: 10}, "kubectl logs orders-7d4b9c-x2k8p -n shop --all-containers -f --tail=10"},
+		// A --since-time from the user is pasted as a single argument
+		{"api", Options{SinceTimestamp: "2024-03-01 10:00:00"}, "kubectl logs orders-7d4b9c-x2k8p -n shop -c api -f '--since-time=2024-03-01 10:00:00'"},
+		{"it's", Options{}, `kubectl logs orders-7d4b9c-x2k8p -n shop -c 'it'\''s' -f`},
+	}
+
+	for _, tt := range tests {
+		if got := kubectlCommand("shop", "orders-7d4b9c-x2k8p", tt.container, tt.opts); got != tt.want {
+			t.Errorf("kubectlCommand(%q) = %q, want %q", tt.container, got, tt.want)
+		}
+	}
+}
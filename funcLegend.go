@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pterm/pterm"
+)
+
+// Sources of the running session, listed by the legend
+var sessionSources []logSource
+
+// Function to print what the colors and styles of the stream mean, and the filters in effect
+func printLegend(opts Options) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
+	pterm.Info.Println("Legend")
+
+	levels := []string{"error", "warning", "panic", "debug", "info"}
+	for i, level := range levels {
+		levels[i] = levelColor(level)(level)
+	}
+	fmt.Printf("  levels:   %s\n", strings.Join(levels, "  "))
+
+	// The keyword as typed, not its regex
+	if keyword := currentKeyword().raw; keyword != "" {
+		fmt.Printf("  keyword:  %s\n", pterm.BgMagenta.Sprint(keyword))
+	}
+	for _, pattern := range activeRules.highlight {
+		fmt.Printf("  rule:     %s\n", pterm.BgMagenta.Sprint(pattern))
+	}
+	if opts.NewErrors {
+		fmt.Printf("  %s:      error with a message not seen before\n", pterm.BgRed.Sprint("NEW"))
+	}
+
+	if showPrefix(opts) {
+		// Numbered for the zoom command
+		for i, src := range sessionSources {
+			state := mutes.state(src)
+			if state != "" {
+				state = pterm.FgDarkGray.Sprintf("  (%s)", state)
+			}
+			fmt.Printf("  %-10s%s %s%s\n", fmt.Sprintf("pod %d:", i+1), sourcePrefix(src, opts), src.Container, state)
+		}
+	}
+
+	var filters []string
+	if filter := activeFilter.Load(); filter != nil {
+		if filter.pods != nil {
+			filters = append(filters, fmt.Sprintf("only pods '%s'", filter.pods))
+		}
+		if filter.containers != nil {
+			filters = append(filters, fmt.Sprintf("only containers '%s'", filter.containers))
+		}
+		if filter.nodes != nil {
+			filters = append(filters, fmt.Sprintf("only nodes '%s'", filter.nodes))
+		}
+	}
+	if len(activeRules.mute) > 0 {
+		filters = append(filters, fmt.Sprintf("%d mute rules", len(activeRules.mute)))
+	}
+	if len(excludePatterns) > 0 {
+		filters = append(filters, fmt.Sprintf("%d excluded patterns", len(excludePatterns)))
+	}
+	if opts.CollapseProbes {
+		filters = append(filters, "probes collapsed")
+	}
+	if level := minimumLevelName(); level != "" {
+		filters = append(filters, level+" lines and above")
+	}
+	if len(filters) == 0 {
+		filters = append(filters, "none")
+	}
+	fmt.Printf("  filters:  %s\n", strings.Join(filters, ", "))
+}
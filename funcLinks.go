@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// Source references like handlers/user.go:123, after the color of a logfmt value kept out of the link
+	fileReference = regexp.MustCompile(`(?:(\x1b\[[0-9;]*m)|\b)((?:[\w.-]+/)*[\w-]+\.(?:go|py|js|ts|rb|rs|c|cc|cpp|h|cs|php|kt|scala|java)):(\d+)\b`)
+	// Java stack frames like at com.shop.OrderService.place(OrderService.java:42)
+	javaFrame = regexp.MustCompile(`\b((?:[a-z_][\w]*\.)+)[A-Z][\w$]*(?:\.[\w$<>]+)?\(([\w$]+\.(?:java|kt|scala)):(\d+)\)`)
+)
+
+// Function to turn the source references of a rendered line into terminal hyperlinks (OSC 8), the
+// template of --code-url giving their URL with {path}, {line} and {revision}
+func linkReferences(text string, revision string, template string) string {
+	if template == "" {
+		return text
+	}
+
+	// A Java frame names the file without its directory, given by the package
+	text = javaFrame.ReplaceAllStringFunc(text, func(frame string) string {
+		match := javaFrame.FindStringSubmatch(frame)
+		path := strings.ReplaceAll(match[1], ".", "/") + match[2]
+		reference := match[2] + ":" + match[3]
+		return strings.Replace(frame, reference, hyperlink(codeURL(template, path, match[3], revision), reference), 1)
+	})
+	if strings.Contains(text, "\033]8;") {
+		return text
+	}
+
+	return fileReference.ReplaceAllStringFunc(text, func(reference string) string {
+		match := fileReference.FindStringSubmatch(reference)
+		return match[1] + hyperlink(codeURL(template, match[2], match[3], revision), strings.TrimPrefix(reference, match[1]))
+	})
+}
+
+func codeURL(template string, path string, line string, revision string) string {
+	return strings.NewReplacer("{path}", strings.TrimPrefix(path, "/"), "{line}", line, "{revision}", revision).Replace(template)
+}
+
+func hyperlink(url string, text string) string {
+	return fmt.Sprintf("\033]8;;%s\033\\%s\033]8;;\033\\", url, text)
+}
@@ -0,0 +1,10 @@
+package main
+
+import "testing"
+
+func TestCodeURLGolden(t *testing.T) {
+	sourceRevisions.Store(fixtureSource, "4f2a9c1")
+	defer sourceRevisions.Delete(fixtureSource)
+
+	checkFlagGolden(t, "code-url", Options{CodeURL: "https://git.example.com/shop/blob/{revision}/{path}#L{line}"}, ruleFile{})
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:     "list [pod-name]",
+	Short:   "List the pods matching a name with their containers.",
+	Example: "  klog list my-api --no-cache\n  klog list deploy/my-api -o json | jq -r '.[].pod'",
+	Args:    cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		pattern := ""
+		if len(args) > 0 {
+			pattern = args[0]
+		}
+		list(pattern, flags)
+	},
+}
+
+// Matched pod as printed by list -o json
+type listedPod struct {
+	Namespace  string    `json:"namespace"`
+	Pod        string    `json:"pod"`
+	Phase      string    `json:"phase"`
+	Restarts   int       `json:"restarts"`
+	Node       string    `json:"node"`
+	Owner      string    `json:"owner,omitempty"`
+	Workload   string    `json:"workload"`
+	Containers []string  `json:"containers"`
+	Streamed   []string  `json:"streamed"`
+	Created    time.Time `json:"created"`
+}
+
+func init() {
+	listCmd.Flags().StringVarP(&flags.Output, "output", "o", "table", "Output format: 'table' or 'json' (pods, containers, streamed containers, nodes and owners)")
+}
+
+// Function to print the matched pods as a table, or as JSON for scripts
+func list(pattern string, opts Options) {
+	if opts.Output != "table" && opts.Output != "json" {
+		pterm.Error.Printf("Invalid output '%s', use table or json\n", opts.Output)
+		os.Exit(1)
+	}
+	if opts.Output == "json" {
+		// Progress and warnings must not mix with the JSON
+		pterm.SetDefaultOutput(os.Stderr)
+	}
+
+	ctx := context.Background()
+	pods := findPods(ctx, newClientset(opts), pattern, opts)
+
+	if opts.Output == "json" {
+		listed := make([]listedPod, len(pods))
+		for i, pod := range pods {
+			listed[i] = listedPod{
+				Namespace:  pod.Namespace,
+				Pod:        pod.Name,
+				Phase:      string(pod.Status.Phase),
+				Restarts:   podRestarts(pod),
+				Node:       pod.Spec.NodeName,
+				Workload:   workloadName(pod),
+				Containers: podContainerNames(pod),
+				Created:    pod.CreationTimestamp.Time,
+			}
+			if owner := metav1.GetControllerOf(&pod); owner != nil {
+				listed[i].Owner = owner.Kind + "/" + owner.Name
+			}
+			for _, src := range podSources(pod, opts) {
+				listed[i].Streamed = append(listed[i].Streamed, src.Container)
+			}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(listed); err != nil {
+			pterm.Error.Printf("Error writing JSON: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	table := pterm.TableData{{"Namespace", "Pod", "Status", "Restarts", "Containers", "Node", "Age"}}
+	for _, pod := range pods {
+		table = append(table, []string{
+			pod.Namespace,
+			pod.Name,
+			string(pod.Status.Phase),
+			fmt.Sprint(podRestarts(pod)),
+			strings.Join(podContainerNames(pod), ","),
+			pod.Spec.NodeName,
+			duration.HumanDuration(time.Since(pod.CreationTimestamp.Time)),
+		})
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(table).Render()
+}
+
+func podRestarts(pod v1.Pod) int {
+	restarts := 0
+	for _, status := range pod.Status.ContainerStatuses {
+		restarts += int(status.RestartCount)
+	}
+	return restarts
+}
+
+func podContainerNames(pod v1.Pod) []string {
+	containers := make([]string, len(pod.Spec.Containers))
+	for i, container := range pod.Spec.Containers {
+		containers[i] = container.Name
+	}
+	return containers
+}
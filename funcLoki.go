@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+const (
+	lokiPageSize     = 5000
+	lokiPollInterval = 2 * time.Second
+	lokiDefaultSince = time.Hour
+	// Range queried again when following, for the lines Loki ingests late
+	lokiFollowOverlap = 30 * time.Second
+)
+
+// Response of the Loki query_range API
+type lokiResponse struct {
+	Data struct {
+		Result []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// Log line returned by Loki with the labels of its stream
+type lokiEntry struct {
+	time time.Time
+	src  logSource
+	line string
+}
+
+// Client of a Loki endpoint, configured like logcli
+type lokiClient struct {
+	baseURL  string
+	username string
+	password string
+	orgID    string
+	// Entries already printed, by time and line, as overlapping queries return them again
+	seen map[lokiKey]bool
+}
+
+type lokiKey struct {
+	time time.Time
+	line string
+}
+
+func newLokiClient(baseURL string) *lokiClient {
+	return &lokiClient{
+		baseURL:  baseURL,
+		username: os.Getenv("LOKI_USERNAME"),
+		password: os.Getenv("LOKI_PASSWORD"),
+		orgID:    os.Getenv("LOKI_ORG_ID"),
+		seen:     make(map[lokiKey]bool),
+	}
+}
+
+// Function to print an entry not printed yet, telling whether it was new
+func (c *lokiClient) print(entry lokiEntry, opts Options) bool {
+	key := lokiKey{time: entry.time, line: entry.line}
+	if c.seen[key] {
+		return false
+	}
+	c.seen[key] = true
+	printLokiEntry(entry, opts)
+	return true
+}
+
+// Function to forget the entries older than the range queried again
+func (c *lokiClient) forget(before time.Time) {
+	for key := range c.seen {
+		if key.time.Before(before) {
+			delete(c.seen, key)
+		}
+	}
+}
+
+// Function to query the entries of a LogQL query between start and end, sorted by time
+func (c *lokiClient) queryRange(ctx context.Context, query string, start time.Time, end time.Time, limit int, direction string) ([]lokiEntry, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", strconv.FormatInt(start.UnixNano(), 10))
+	params.Set("end", strconv.FormatInt(end.UnixNano(), 10))
+	params.Set("limit", strconv.Itoa(limit))
+	params.Set("direction", direction)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/loki/api/v1/query_range?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.username != "" {
+		request.SetBasicAuth(c.username, c.password)
+	}
+	if c.orgID != "" {
+		request.Header.Set("X-Scope-OrgID", c.orgID)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("loki returned %s", response.Status)
+	}
+
+	var result lokiResponse
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	var entries []lokiEntry
+	for _, stream := range result.Data.Result {
+		src := logSource{
+			Namespace: stream.Stream["namespace"],
+			Pod:       stream.Stream["pod"],
+			Container: stream.Stream["container"],
+			Node:      stream.Stream["node_name"],
+		}
+		for _, value := range stream.Values {
+			nanos, err := strconv.ParseInt(value[0], 10, 64)
+			if err != nil {
+				continue
+			}
+			entries = append(entries, lokiEntry{time: time.Unix(0, nanos), src: src, line: value[1]})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].time.Before(entries[j].time) })
+	return entries, nil
+}
+
+// Function to print the entries of a query from start until end not printed yet, page by page
+func (c *lokiClient) printRange(ctx context.Context, query string, start time.Time, end time.Time, opts Options) error {
+	for {
+		entries, err := c.queryRange(ctx, query, start, end, lokiPageSize, "forward")
+		if err != nil {
+			return err
+		}
+		printed := 0
+		for _, entry := range entries {
+			if c.print(entry, opts) {
+				printed++
+			}
+		}
+		if len(entries) < lokiPageSize {
+			return nil
+		}
+
+		// The next page starts at the last time, other lines of that time may be left
+		last := entries[len(entries)-1].time
+		if printed == 0 && !last.After(start) {
+			// A full page of lines of a single time, the rest of them can't be reached
+			last = last.Add(time.Nanosecond)
+		}
+		start = last
+	}
+}
+
+func printLokiEntry(entry lokiEntry, opts Options) {
+	line := entry.line
+	if withTimestamps(opts) {
+		line = entry.time.Format(time.RFC3339Nano) + " " + line
+	}
+	printLogLine(entry.src, line, opts)
+}
+
+// Function to stream the result of a LogQL query through the same rendering as pod logs
+func streamLoki(opts Options) {
+	if opts.LokiURL == "" {
+		pterm.Error.Println("Loki address required, use --loki-url or LOKI_ADDR")
+		os.Exit(1)
+	}
+	if opts.Query == "" {
+		pterm.Error.Println("LogQL query required, use --query")
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	client := newLokiClient(opts.LokiURL)
+
+	// Loki queries usually span several pods, prefix lines like -a
+	opts.AllPods = true
+
+	since := lokiDefaultSince
+	if opts.Since > 0 {
+		since = opts.Since
+	}
+	start := time.Now().Add(-since)
+	if sinceTime, ok := sinceTimestamp(opts); ok {
+		start = sinceTime
+	}
+	end := time.Now()
+
+	startSession(ctx, nil, nil, opts)
+
+	if opts.TailLines > 0 {
+		entries, err := client.queryRange(ctx, opts.Query, start, end, opts.TailLines, "backward")
+		if err != nil {
+			pterm.Error.Printf("Error querying Loki: %v\n", err)
+			os.Exit(1)
+		}
+		for _, entry := range entries {
+			client.print(entry, opts)
+		}
+	} else if err := client.printRange(ctx, opts.Query, start, end, opts); err != nil {
+		pterm.Error.Printf("Error querying Loki: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Follow by polling the range since the previous query, with an overlap for the lines ingested late
+	for {
+		time.Sleep(lokiPollInterval)
+		next := time.Now()
+		from := end.Add(-lokiFollowOverlap)
+		if from.Before(start) {
+			from = start
+		}
+		if err := client.printRange(ctx, opts.Query, from, next, opts); err != nil {
+			pterm.Warning.Printf("Error querying Loki: %v\n", err)
+			continue
+		}
+		client.forget(from)
+		end = next
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/pterm/pterm"
+)
+
+// Streams muted or soloed at runtime, with the number of lines suppressed for each
+type streamMutes struct {
+	mu         sync.Mutex
+	muted      map[logSource]bool
+	solo       *logSource
+	suppressed map[logSource]int
+}
+
+var mutes = &streamMutes{muted: make(map[logSource]bool), suppressed: make(map[logSource]int)}
+
+// Function to count and suppress a line of a muted stream, or of another stream than the soloed one
+func (m *streamMutes) suppress(src logSource) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.muted[src] || (m.solo != nil && *m.solo != src) {
+		m.suppressed[src]++
+		return true
+	}
+	return false
+}
+
+// Function to describe the mute state of a stream for the legend, empty when displayed
+func (m *streamMutes) state(src logSource) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case m.muted[src]:
+		return "muted, " + strconv.Itoa(m.suppressed[src]) + " lines suppressed"
+	case m.solo != nil && *m.solo == src:
+		return "solo"
+	case m.solo != nil:
+		return "hidden by solo, " + strconv.Itoa(m.suppressed[src]) + " lines suppressed"
+	}
+	return ""
+}
+
+// Function to parse the stream number of a mute or solo command
+func streamNumber(arg string) (logSource, bool) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 || n > len(sessionSources) {
+		pterm.Warning.Printf("No stream '%s', type l to list them\n", arg)
+		return logSource{}, false
+	}
+	return sessionSources[n-1], true
+}
+
+// Function to mute the n-th stream of the legend, or to unmute it
+func toggleMute(arg string) {
+	src, ok := streamNumber(arg)
+	if !ok {
+		return
+	}
+
+	mutes.mu.Lock()
+	defer mutes.mu.Unlock()
+	if mutes.muted[src] {
+		delete(mutes.muted, src)
+		pterm.Info.Printf("Pod '%s' container '%s' unmuted, %d lines were suppressed\n", src.Pod, src.Container, mutes.suppressed[src])
+		mutes.suppressed[src] = 0
+		return
+	}
+	mutes.muted[src] = true
+	pterm.Info.Printf("Pod '%s' container '%s' muted, type mute %s again to unmute it\n", src.Pod, src.Container, arg)
+}
+
+// Function to only display the n-th stream of the legend, or to display every stream again
+func toggleSolo(arg string) {
+	src, ok := streamNumber(arg)
+	if !ok {
+		return
+	}
+
+	mutes.mu.Lock()
+	defer mutes.mu.Unlock()
+	if mutes.solo != nil && *mutes.solo == src {
+		mutes.solo = nil
+		total := 0
+		for _, source := range sessionSources {
+			if !mutes.muted[source] {
+				total += mutes.suppressed[source]
+				mutes.suppressed[source] = 0
+			}
+		}
+		pterm.Info.Printf("Solo of pod '%s' ended, %d lines of the other streams were suppressed\n", src.Pod, total)
+		return
+	}
+	mutes.solo = &src
+	pterm.Info.Printf("Only displaying pod '%s' container '%s', type solo %s again to display every stream\n", src.Pod, src.Container, arg)
+}
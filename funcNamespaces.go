@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/pterm/pterm"
+)
+
+// Namespaces of the config whose pods are only streamed with --force: the denied ones,
+// and all but the allowed ones when an allow-list is set. Both hold regexes of whole names.
+type namespaceRules struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// Function to merge the namespace rules of the shared config and of the local rules file
+func mergeNamespaceRules(shared, local *namespaceRules) *namespaceRules {
+	merged := &namespaceRules{}
+	for _, rules := range []*namespaceRules{shared, local} {
+		if rules != nil {
+			merged.Allow = append(merged.Allow, rules.Allow...)
+			merged.Deny = append(merged.Deny, rules.Deny...)
+		}
+	}
+	return merged
+}
+
+func compileNamespacePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespace pattern '%s': %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// Function to tell whether the pods of a namespace need --force
+func (c *compiledRules) protectedNamespace(namespace string) bool {
+	for _, re := range c.denyNamespaces {
+		if re.MatchString(namespace) {
+			return true
+		}
+	}
+	if len(c.allowNamespaces) == 0 {
+		return false
+	}
+	for _, re := range c.allowNamespaces {
+		if re.MatchString(namespace) {
+			return false
+		}
+	}
+	return true
+}
+
+// Function to leave out the pods of protected namespaces unless --force is set
+func guardNamespaces(pods []v1.Pod, opts Options) []v1.Pod {
+	if opts.Force {
+		return pods
+	}
+
+	var allowed []v1.Pod
+	protected := make(map[string]bool)
+	for _, pod := range pods {
+		if activeRules.protectedNamespace(pod.Namespace) {
+			protected[pod.Namespace] = true
+		} else {
+			allowed = append(allowed, pod)
+		}
+	}
+	if len(protected) == 0 {
+		return pods
+	}
+
+	namespaces := make([]string, 0, len(protected))
+	for namespace := range protected {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	if len(allowed) == 0 {
+		pterm.Error.Printf("Pods matched only in protected namespaces (%s), use --force to stream them\n", strings.Join(namespaces, ", "))
+		os.Exit(1)
+	}
+	pterm.Warning.Printf("Left out the pods of protected namespaces (%s), use --force to stream them\n", strings.Join(namespaces, ", "))
+	return allowed
+}
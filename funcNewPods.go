@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/pterm/pterm"
+)
+
+// Delay before watching the pods again after the watch failed
+const newPodsRetryDelay = 5 * time.Second
+
+// Function to attach the pods matching the pattern created during the session, e.g. by scaling or rollouts
+func watchNewPods(ctx context.Context, clientset *kubernetes.Clientset, pods []v1.Pod, pattern string, opts Options, attach func(v1.Pod)) {
+	var mu sync.Mutex
+	known := make(map[string]bool)
+	for _, pod := range pods {
+		known[pod.Namespace+"/"+pod.Name] = true
+	}
+
+	namespaces := searchNamespaces(opts)
+
+	// Function to attach a pod seen for the first time if it matches
+	checkNewPod := func(pod *v1.Pod) {
+		if pod.DeletionTimestamp != nil {
+			return
+		}
+		mu.Lock()
+		seen := known[pod.Namespace+"/"+pod.Name]
+		known[pod.Namespace+"/"+pod.Name] = true
+		mu.Unlock()
+		if seen {
+			return
+		}
+
+		matched, err := matchTarget(ctx, clientset, []v1.Pod{*pod}, pattern, namespaces, opts)
+		if err != nil || len(matched) == 0 || (!opts.Force && activeRules.protectedNamespace(pod.Namespace)) {
+			return
+		}
+		pterm.Info.Printf("New pod '%s' matched, attaching its logs\n", pod.Name)
+		attach(*pod)
+	}
+
+	var wg sync.WaitGroup
+	for _, namespace := range namespaces {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			listOptions := metav1.ListOptions{LabelSelector: opts.Selector, FieldSelector: opts.FieldSelector}
+			started := false
+			for ctx.Err() == nil {
+				// The pods listed at the start are the ones of the session, those created while
+				// the watch was down are checked like new ones
+				list, err := clientset.CoreV1().Pods(namespace).List(ctx, listOptions)
+				if err == nil {
+					for i := range list.Items {
+						if !started {
+							mu.Lock()
+							known[list.Items[i].Namespace+"/"+list.Items[i].Name] = true
+							mu.Unlock()
+						} else {
+							checkNewPod(&list.Items[i])
+						}
+					}
+					started = true
+
+					watchOptions := listOptions
+					watchOptions.ResourceVersion = list.ResourceVersion
+					var watcher watch.Interface
+					if watcher, err = clientset.CoreV1().Pods(namespace).Watch(ctx, watchOptions); err == nil {
+						for event := range watcher.ResultChan() {
+							if pod, ok := event.Object.(*v1.Pod); ok && event.Type != watch.Deleted {
+								checkNewPod(pod)
+							}
+						}
+						watcher.Stop()
+						continue
+					}
+				}
+				pterm.Warning.Printf("Unable to watch for new pods: %v\n", err)
+				time.Sleep(newPodsRetryDelay)
+			}
+		}()
+	}
+	wg.Wait()
+}
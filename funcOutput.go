@@ -0,0 +1,275 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// Interval of the flushes of the compressed output files, readable up to the last flush if klog is killed
+const outputFlushInterval = time.Second
+
+// Files the displayed log lines are copied to with --output-file and --output-dir
+type outputCopy struct {
+	file     *rotatingFile
+	plain    bool
+	only     bool
+	dir      string
+	maxSize  int64
+	maxFiles int
+	compress bool
+	// Files of --output-dir, opened at the first line of each stream
+	streams map[logSource]*rotatingFile
+}
+
+// Output file renamed to <path>.1, <path>.2, ... (<path>.1.gz, ... when compressed) when it
+// reaches --output-max-size of uncompressed lines
+type rotatingFile struct {
+	path     string
+	file     *os.File
+	gz       *gzip.Writer
+	writer   io.Writer
+	size     int64
+	maxSize  int64
+	maxFiles int
+}
+
+var output *outputCopy
+
+var (
+	// Lines printed as NDJSON records with -o json
+	jsonLines bool
+	// Writer of the markers inserted in the stream (rollouts, exec, probes), stderr with -o json
+	markerOutput io.Writer = os.Stdout
+)
+
+// Function to check -o and keep stdout for the NDJSON records with -o json
+func initLineOutput(opts Options) {
+	switch opts.LineOutput {
+	case "text":
+	case "json":
+		jsonLines = true
+		markerOutput = os.Stderr
+		pterm.SetDefaultOutput(os.Stderr)
+	default:
+		pterm.Error.Printf("Invalid output '%s', use text or json\n", opts.LineOutput)
+		os.Exit(1)
+	}
+}
+
+// Function to get the text of a line, its record as JSON with -o json
+func (p printedLine) text(keyword *keywordHighlight) string {
+	if !jsonLines {
+		return p.render(keyword)
+	}
+	data, _ := json.Marshal(p.record())
+	return string(data)
+}
+
+// Function to create the --output-file and --output-dir receiving a copy of the displayed lines
+func startOutput(opts Options) {
+	if opts.OutputFile == "" && opts.OutputDir == "" {
+		if opts.OutputOnly {
+			pterm.Error.Println("--output-only requires --output-file or --output-dir")
+			os.Exit(1)
+		}
+		return
+	}
+
+	output = &outputCopy{
+		plain:    opts.OutputPlain,
+		only:     opts.OutputOnly,
+		dir:      opts.OutputDir,
+		maxSize:  int64(opts.OutputMaxSize) * 1024 * 1024,
+		maxFiles: opts.OutputMaxFiles,
+		compress: opts.OutputCompress,
+		streams:  make(map[logSource]*rotatingFile),
+	}
+	onSessionEnd(func() {
+		outputMu.Lock()
+		closeOutput()
+		outputMu.Unlock()
+	})
+	if output.compress {
+		go func() {
+			for range time.Tick(outputFlushInterval) {
+				outputMu.Lock()
+				if output != nil {
+					output.flush()
+				}
+				outputMu.Unlock()
+			}
+		}()
+	}
+	if opts.OutputFile != "" {
+		file, err := output.create(opts.OutputFile)
+		if err != nil {
+			pterm.Error.Printf("Error creating output file: %v\n", err)
+			os.Exit(1)
+		}
+		output.file = file
+		pterm.Info.Printf("Copying the displayed lines to %s\n", file.path)
+	}
+	if opts.OutputDir != "" {
+		if err := os.MkdirAll(opts.OutputDir, 0o755); err != nil {
+			pterm.Error.Printf("Error creating output directory: %v\n", err)
+			os.Exit(1)
+		}
+		pterm.Info.Printf("Copying the lines of each stream to %s\n", opts.OutputDir)
+	}
+}
+
+// Function to print a log line and copy it to the output files, with outputMu held
+func displayLine(line printedLine, keyword *keywordHighlight) {
+	text := line.text(keyword)
+	if output == nil || !output.only {
+		fmt.Println(text)
+	}
+	// /stream subscribers receive the lines left by the filters, like the terminal
+	if streamHub != nil {
+		streamHub.publish(line.record())
+	}
+	if output == nil {
+		return
+	}
+
+	if output.file != nil {
+		if err := output.write(output.file, text); err != nil {
+			pterm.Warning.Printf("Error writing output file, no longer copying lines: %v\n", err)
+			output.file = nil
+		}
+	}
+
+	if output.dir != "" {
+		file, ok := output.streams[line.src]
+		if !ok {
+			name := fmt.Sprintf("%s_%s_%s.log", line.src.Namespace, line.src.Pod, line.src.Container)
+			var err error
+			if file, err = output.create(filepath.Join(output.dir, name)); err != nil {
+				pterm.Warning.Printf("Error creating output file of pod '%s': %v\n", line.src.Pod, err)
+			}
+			output.streams[line.src] = file
+		}
+		if file != nil {
+			// The file already tells the stream of its lines
+			line.prefix = ""
+			if err := output.write(file, line.text(keyword)); err != nil {
+				pterm.Warning.Printf("Error writing output file of pod '%s', no longer copying its lines: %v\n", line.src.Pod, err)
+				output.streams[line.src] = nil
+			}
+		}
+	}
+}
+
+func (o *outputCopy) create(path string) (*rotatingFile, error) {
+	if o.compress {
+		path += ".gz"
+	}
+	f := &rotatingFile{path: path, maxSize: o.maxSize, maxFiles: o.maxFiles}
+	if err := f.open(o.compress); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Function to flush the compressed output files, with outputMu held
+func (o *outputCopy) flush() {
+	for _, f := range o.files() {
+		if f.gz != nil {
+			_ = f.gz.Flush()
+		}
+	}
+}
+
+// Function to complete the output files once the streams ended, with outputMu held
+func closeOutput() {
+	if output == nil {
+		return
+	}
+	for _, f := range output.files() {
+		_ = f.close()
+	}
+	output = nil
+}
+
+func (o *outputCopy) files() []*rotatingFile {
+	var files []*rotatingFile
+	if o.file != nil {
+		files = append(files, o.file)
+	}
+	for _, f := range o.streams {
+		if f != nil {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+func (f *rotatingFile) open(compress bool) error {
+	file, err := os.Create(f.path)
+	if err != nil {
+		return err
+	}
+	f.file, f.writer, f.size = file, file, 0
+	if compress {
+		f.gz = gzip.NewWriter(file)
+		f.writer = f.gz
+	}
+	return nil
+}
+
+func (f *rotatingFile) close() error {
+	if f.gz != nil {
+		if err := f.gz.Close(); err != nil {
+			f.file.Close()
+			return err
+		}
+	}
+	return f.file.Close()
+}
+
+// Function to get the name of the n-th rotated file, keeping the .gz extension last
+func (f *rotatingFile) rotatedPath(n int) string {
+	if base, ok := strings.CutSuffix(f.path, ".gz"); ok && f.gz != nil {
+		return fmt.Sprintf("%s.%d.gz", base, n)
+	}
+	return fmt.Sprintf("%s.%d", f.path, n)
+}
+
+func (o *outputCopy) write(file *rotatingFile, text string) error {
+	if o.plain {
+		text = pterm.RemoveColorFromString(text)
+	}
+	if file.maxSize > 0 && file.size > 0 && file.size+int64(len(text))+1 > file.maxSize {
+		if err := file.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := fmt.Fprintln(file.writer, text)
+	file.size += int64(n)
+	return err
+}
+
+// Function to shift the rotated files, dropping the oldest beyond --output-max-files, and start a new file
+func (f *rotatingFile) rotate() error {
+	if err := f.close(); err != nil {
+		return err
+	}
+	_ = os.Remove(f.rotatedPath(f.maxFiles))
+	for i := f.maxFiles - 1; i >= 1; i-- {
+		_ = os.Rename(f.rotatedPath(i), f.rotatedPath(i+1))
+	}
+	if f.maxFiles > 0 {
+		if err := os.Rename(f.path, f.rotatedPath(1)); err != nil {
+			return err
+		}
+	}
+	return f.open(f.gz != nil)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"testing"
+)
+
+func TestRotatedPath(t *testing.T) {
+	tests := []struct {
+		path       string
+		compressed bool
+		n          int
+		want       string
+	}{
+		{"klog.log", false, 1, "klog.log.1"},
+		{"out/shop_orders_api.log", false, 5, "out/shop_orders_api.log.5"},
+		{"klog.log.gz", true, 1, "klog.log.1.gz"},
+		{"out/shop_orders_api.log.gz", true, 12, "out/shop_orders_api.log.12.gz"},
+		// A .gz name written uncompressed keeps its extension in the middle
+		{"archive.gz", false, 2, "archive.gz.2"},
+	}
+
+	for _, tt := range tests {
+		f := &rotatingFile{path: tt.path}
+		if tt.compressed {
+			f.gz = gzip.NewWriter(io.Discard)
+		}
+		if got := f.rotatedPath(tt.n); got != tt.want {
+			t.Errorf("rotatedPath(%q, %d) = %q, want %q", tt.path, tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// How long a line with a request ID waits for the line of another container of its pod with the same ID
+const pairWindow = 2 * time.Second
+
+// Keys of the JSON and logfmt fields holding a request ID, compared in lower case
+var requestIDKeys = []string{"request_id", "requestid", "request-id", "x-request-id", "x_request_id", "req_id", "reqid"}
+
+// Request IDs of the proxies logged without key, like the x-request-id UUID of Envoy access logs
+var requestUUID = regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)
+
+// Lines with a request ID held back until the line of the other container arrives
+type requestPairer struct {
+	mu   sync.Mutex
+	held map[string]heldRequest
+	opts Options
+	stop chan struct{}
+}
+
+type heldRequest struct {
+	line     printedLine
+	received time.Time
+}
+
+var pairer *requestPairer
+
+// Function to display together with --pair-requests the lines of the containers of a pod sharing a request ID
+func startPairing(opts Options) {
+	if !opts.PairRequests {
+		return
+	}
+	if !opts.AllContainers {
+		pterm.Error.Println("--pair-requests requires --all-containers, to stream the application and its proxy")
+		os.Exit(1)
+	}
+	if opts.Sort {
+		pterm.Error.Println("--pair-requests and --sort exclude each other")
+		os.Exit(1)
+	}
+
+	pairer = &requestPairer{held: make(map[string]heldRequest), opts: opts, stop: make(chan struct{})}
+	onSessionEnd(pairer.drain)
+	go func() {
+		ticker := time.NewTicker(pairWindow / 10)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-pairer.stop:
+				return
+			case <-ticker.C:
+				pairer.flush(time.Now().Add(-pairWindow))
+			}
+		}
+	}()
+}
+
+// Function to get the request ID of a line in lower case, from its fields or else a UUID of its text
+func requestID(line printedLine) string {
+	for key, value := range line.fields {
+		if text, ok := value.(string); ok && text != "" {
+			for _, idKey := range requestIDKeys {
+				if strings.EqualFold(key, idKey) {
+					return strings.ToLower(text)
+				}
+			}
+		}
+	}
+	return strings.ToLower(requestUUID.FindString(line.line))
+}
+
+// Function to hold back a line with a request ID, or to display it with the held line of the other
+// container, true when the line was taken
+func (p *requestPairer) hold(line printedLine) bool {
+	id := requestID(line)
+	if id == "" {
+		return false
+	}
+	key := line.src.Namespace + "/" + line.src.Pod + "/" + id
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	held, ok := p.held[key]
+	if !ok || held.line.src.Container == line.src.Container {
+		if ok {
+			p.display(held.line)
+		}
+		p.held[key] = heldRequest{line: line, received: time.Now()}
+		return true
+	}
+
+	delete(p.held, key)
+	tag := " " + pterm.FgDarkGray.Sprintf("⇄ %s", shortRequestID(id))
+	held.line.tag += tag
+	line.tag += tag
+	p.display(held.line, line)
+	return true
+}
+
+// Function to display the lines held longer than the limit without a pair, in the order they were received
+func (p *requestPairer) flush(limit time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var expired []heldRequest
+	for key, held := range p.held {
+		if !held.received.After(limit) {
+			delete(p.held, key)
+			expired = append(expired, held)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].received.Before(expired[j].received) })
+	for _, held := range expired {
+		p.display(held.line)
+	}
+}
+
+// Function to display every line still held back and stop waiting for pairs, once the session ends
+func (p *requestPairer) drain() {
+	close(p.stop)
+	p.flush(time.Now())
+}
+
+func (p *requestPairer) display(lines ...printedLine) {
+	keyword := currentKeyword()
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	for _, line := range lines {
+		displayLine(line, keyword)
+	}
+}
+
+// Function to shorten a request ID for the pair tag
+func shortRequestID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
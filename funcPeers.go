@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/pterm/pterm"
+)
+
+// Peer rule of the config: the pods matching pod also talk to the pods matching the with regexes
+type peerRule struct {
+	Pod  string   `json:"pod"`
+	With []string `json:"with"`
+}
+
+type compiledPeerRule struct {
+	pod  *regexp.Regexp
+	with []*regexp.Regexp
+}
+
+// Pod related to the followed one, with how it was found
+type peerPod struct {
+	pod      v1.Pod
+	relation string
+}
+
+// Function to compile the regexes of a peer rule
+func compilePeerRule(r peerRule) (compiledPeerRule, error) {
+	pod, err := regexp.Compile(r.Pod)
+	if err != nil {
+		return compiledPeerRule{}, fmt.Errorf("invalid peer pod '%s': %w", r.Pod, err)
+	}
+	if len(r.With) == 0 {
+		return compiledPeerRule{}, fmt.Errorf("peer rule of pod '%s' has no peer", r.Pod)
+	}
+	compiled := compiledPeerRule{pod: pod}
+	for _, with := range r.With {
+		re, err := regexp.Compile(with)
+		if err != nil {
+			return compiledPeerRule{}, fmt.Errorf("invalid peer '%s': %w", with, err)
+		}
+		compiled.with = append(compiled.with, re)
+	}
+	return compiled, nil
+}
+
+// Function to offer the peers of the followed pod with --with-peers, returning the ones to stream with it
+func choosePeers(ctx context.Context, clientset *kubernetes.Clientset, pod v1.Pod, opts Options) []v1.Pod {
+	if !opts.WithPeers {
+		return nil
+	}
+
+	peers, err := findPeers(ctx, clientset, pod, opts)
+	if err != nil {
+		pterm.Warning.Printf("Unable to find the peers of pod '%s': %v\n", pod.Name, err)
+		return nil
+	}
+	if len(peers) == 0 {
+		pterm.Info.Printf("No peer found for pod '%s', through its services, environment or peer rules\n", pod.Name)
+		return nil
+	}
+
+	table := pterm.TableData{{"Pod", "Namespace", "Relation"}}
+	options := make([]string, len(peers))
+	for i, peer := range peers {
+		table = append(table, []string{peer.pod.Name, peer.pod.Namespace, peer.relation})
+		options[i] = fmt.Sprintf("%s (%s)", peer.pod.Name, peer.relation)
+	}
+	pterm.Info.Printf("%d peers of pod '%s':\n", len(peers), pod.Name)
+	_ = pterm.DefaultTable.WithHasHeader().WithData(table).Render()
+
+	if opts.NonInteractive || opts.Yes {
+		pods := make([]v1.Pod, len(peers))
+		for i, peer := range peers {
+			pods[i] = peer.pod
+		}
+		return pods
+	}
+
+	selected, _ := pterm.DefaultInteractiveMultiselect.
+		WithDefaultText("Peers to stream with the pod (Enter toggles, Tab confirms)").
+		WithOptions(options).
+		WithDefaultOptions(options).
+		WithMaxHeight(15).
+		Show()
+	var pods []v1.Pod
+	for i, option := range options {
+		for _, s := range selected {
+			if s == option {
+				pods = append(pods, peers[i].pod)
+				break
+			}
+		}
+	}
+	return pods
+}
+
+// Function to find the pods a pod talks to and the ones talking to it: the pods behind the services
+// named in its environment, command and arguments (upstream), the pods naming the services it is
+// behind (downstream), and the pods of the peer rules
+func findPeers(ctx context.Context, clientset *kubernetes.Clientset, pod v1.Pod, opts Options) ([]peerPod, error) {
+	// The whole cluster when allowed, else the namespace of the pod
+	namespace := metav1.NamespaceAll
+	endpoints, err := clientset.CoreV1().Endpoints(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		namespace = pod.Namespace
+		endpoints, err = clientset.CoreV1().Endpoints(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	podList, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pods := make(map[string]v1.Pod, len(podList.Items))
+	for _, p := range podList.Items {
+		pods[p.Namespace+"/"+p.Name] = p
+	}
+
+	// Pods behind each service, from the target references of its endpoints
+	backends := make(map[string][]string)
+	var serving []metav1.ObjectMeta
+	for _, e := range endpoints.Items {
+		for _, subset := range e.Subsets {
+			for _, address := range append(subset.Addresses, subset.NotReadyAddresses...) {
+				if address.TargetRef == nil || address.TargetRef.Kind != "Pod" {
+					continue
+				}
+				key := e.Namespace + "/" + address.TargetRef.Name
+				backends[e.Namespace+"/"+e.Name] = append(backends[e.Namespace+"/"+e.Name], key)
+				if key == pod.Namespace+"/"+pod.Name {
+					serving = append(serving, e.ObjectMeta)
+				}
+			}
+		}
+	}
+
+	found := make(map[string]string)
+	add := func(key string, relation string) {
+		if _, ok := found[key]; !ok && key != pod.Namespace+"/"+pod.Name {
+			found[key] = relation
+		}
+	}
+
+	// Patterns of the service hosts, per service and namespace of the pod naming them
+	hostPatterns := make(map[[3]string]*regexp.Regexp)
+	mentions := func(p v1.Pod, name string, namespace string) bool {
+		key := [3]string{name, namespace, p.Namespace}
+		if hostPatterns[key] == nil {
+			hostPatterns[key] = serviceHostPattern(name, namespace, p.Namespace)
+		}
+		text := podReferences(p)
+		for _, match := range hostPatterns[key].FindAllStringSubmatchIndex(text, -1) {
+			// A scheme like redis:// is not a host
+			if !strings.HasPrefix(text[match[len(match)-2]:], "://") {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, e := range endpoints.Items {
+		if mentions(pod, e.Name, e.Namespace) {
+			for _, key := range backends[e.Namespace+"/"+e.Name] {
+				add(key, "upstream, service "+e.Name)
+			}
+		}
+	}
+	for _, service := range serving {
+		for key, p := range pods {
+			if mentions(p, service.Name, service.Namespace) {
+				add(key, "downstream, calls service "+service.Name)
+			}
+		}
+	}
+	for _, r := range activeRules.peers {
+		if !r.pod.MatchString(pod.Name) {
+			continue
+		}
+		for key, p := range pods {
+			for _, with := range r.with {
+				if with.MatchString(p.Name) {
+					add(key, "peer rule "+with.String())
+				}
+			}
+		}
+	}
+
+	var peers []peerPod
+	for key, relation := range found {
+		p, ok := pods[key]
+		if !ok || p.DeletionTimestamp != nil || (!opts.Force && activeRules.protectedNamespace(p.Namespace)) {
+			continue
+		}
+		if len(excludePods([]v1.Pod{p}, opts.ExcludePods)) == 0 {
+			continue
+		}
+		peers = append(peers, peerPod{pod: p, relation: relation})
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		if peers[i].relation != peers[j].relation {
+			return peers[i].relation < peers[j].relation
+		}
+		return peers[i].pod.Name < peers[j].pod.Name
+	})
+	return peers, nil
+}
+
+// Function to gather the texts of a pod where the hosts it calls are configured
+func podReferences(pod v1.Pod) string {
+	var texts []string
+	for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+		texts = append(texts, container.Command...)
+		texts = append(texts, container.Args...)
+		for _, env := range container.Env {
+			texts = append(texts, env.Value)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// Function to match a service named as a host, by its name alone from its own namespace
+// or by <name>.<namespace>[.svc[.cluster.local]]
+func serviceHostPattern(name string, namespace string, from string) *regexp.Regexp {
+	host := regexp.QuoteMeta(name) + `\.` + regexp.QuoteMeta(namespace) + `(\.svc(\.cluster\.local)?)?`
+	if namespace == from {
+		host = regexp.QuoteMeta(name) + `(\.` + regexp.QuoteMeta(namespace) + `(\.svc(\.cluster\.local)?)?)?`
+	}
+	return regexp.MustCompile(`(^|[^\w.-])` + host + `($|[^\w.-])`)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/pterm/pterm"
+)
+
+// Function to print an overview of the matched pods with --prescreen and let the user leave some out before streaming
+func prescreenPods(pods []v1.Pod, opts Options) []v1.Pod {
+	if !opts.Prescreen || !opts.AllPods || len(pods) == 0 {
+		return pods
+	}
+
+	phases := make(map[string]int)
+	images := make(map[string]int)
+	table := pterm.TableData{{"Pod", "Status", "Restarts", "Images"}}
+	for _, pod := range pods {
+		phases[string(pod.Status.Phase)]++
+		var podImages []string
+		for _, container := range pod.Spec.Containers {
+			images[container.Image]++
+			podImages = append(podImages, container.Image)
+		}
+		table = append(table, []string{pod.Name, string(pod.Status.Phase), fmt.Sprint(podRestarts(pod)), strings.Join(podImages, ", ")})
+	}
+
+	var phaseCounts, imageCounts []string
+	for phase, count := range phases {
+		phaseCounts = append(phaseCounts, fmt.Sprintf("%d %s", count, phase))
+	}
+	for image, count := range images {
+		imageCounts = append(imageCounts, fmt.Sprintf("%s (%d/%d)", image, count, len(pods)))
+	}
+	sort.Strings(phaseCounts)
+	sort.Strings(imageCounts)
+
+	pterm.Info.Printf("%d pods matched: %s\n", len(pods), strings.Join(phaseCounts, ", "))
+	// Replicas running different images are often the ones worth a look
+	pterm.Info.Printf("Images: %s\n", strings.Join(imageCounts, ", "))
+	_ = pterm.DefaultTable.WithHasHeader().WithData(table).Render()
+
+	if opts.NonInteractive || opts.Yes {
+		return pods
+	}
+
+	options := make([]string, len(pods))
+	for i, pod := range pods {
+		options[i] = fmt.Sprintf("%s (%s, %d restarts)", pod.Name, pod.Status.Phase, podRestarts(pod))
+	}
+	selected, _ := pterm.DefaultInteractiveMultiselect.
+		WithDefaultText("Pods to stream (Enter toggles, Tab confirms)").
+		WithOptions(options).
+		WithDefaultOptions(options).
+		WithMaxHeight(15).
+		Show()
+
+	chosen := make(map[string]bool)
+	for _, option := range selected {
+		chosen[option] = true
+	}
+	var kept []v1.Pod
+	for i, pod := range pods {
+		if chosen[options[i]] {
+			kept = append(kept, pod)
+		}
+	}
+	if len(kept) == 0 {
+		pterm.Info.Println("No pod selected, nothing to stream")
+		os.Exit(0)
+	}
+	return kept
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/pterm/pterm"
+)
+
+// Function to re-indent a single-line JSON object or array and color its keys, strings and numbers,
+// false when the line isn't JSON
+func renderPrettyJSON(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return "", false
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, []byte(trimmed), "", "  "); err != nil {
+		return "", false
+	}
+
+	text := indented.String()
+	var out strings.Builder
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '"':
+			end := i + 1
+			for end < len(text) && text[end] != '"' {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end++
+			token := text[i:end]
+			// A string followed by a colon is a key
+			if strings.HasPrefix(text[end:], ":") {
+				out.WriteString(pterm.FgCyan.Sprint(token))
+			} else {
+				out.WriteString(pterm.FgGreen.Sprint(token))
+			}
+			i = end
+		case c == '-' || c >= '0' && c <= '9':
+			end := i + 1
+			for end < len(text) && strings.IndexByte("0123456789.eE+-", text[end]) >= 0 {
+				end++
+			}
+			out.WriteString(pterm.FgYellow.Sprint(text[i:end]))
+			i = end
+		case c == 't' || c == 'f' || c == 'n':
+			end := i + 1
+			for end < len(text) && text[end] >= 'a' && text[end] <= 'z' {
+				end++
+			}
+			out.WriteString(pterm.FgMagenta.Sprint(text[i:end]))
+			i = end
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return out.String(), true
+}
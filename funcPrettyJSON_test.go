@@ -0,0 +1,21 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/pterm/pterm"
+)
+
+func TestPrettyJSONGolden(t *testing.T) {
+	checkFlagGolden(t, "pretty-json", Options{PrettyJSON: true}, ruleFile{})
+}
+
+func TestPrettyJSONKeywordGolden(t *testing.T) {
+	pterm.EnableColor()
+
+	// The keyword is highlighted over the pretty-printed JSON instead of disabling it
+	fixture := filepath.Join("testdata", "fixtures", "flags", "pretty-json.log")
+	got := renderFixture(t, fixture, "orders|not JSON", Options{PrettyJSON: true}, ruleFile{})
+	compareGolden(t, got, fixture, filepath.Join("testdata", "golden", "flags", "pretty-json-keyword.golden"))
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// Interval of the summary lines replacing the probe requests
+const probeInterval = time.Minute
+
+var (
+	probeRequest = regexp.MustCompile(`kube-probe/|/(healthz|livez|readyz|health|ready|live)\b`)
+	probeStatus  = regexp.MustCompile(`(?i)(?:status(?:_?code)?"?[=:]\s*"?|HTTP/[\d.]+"\s+)([1-5]\d\d)\b`)
+)
+
+// Count the probe requests of each stream until the next summary
+type probeTracker struct {
+	mu     sync.Mutex
+	counts map[probeKey]*probeCount
+	opts   Options
+}
+
+type probeKey struct {
+	src  logSource
+	kind string
+}
+
+type probeCount struct {
+	ok     int
+	failed int
+}
+
+var probes *probeTracker
+
+// Function to collapse the probe requests into a summary line per stream and probe kind every minute
+func startProbeSummaries(opts Options) {
+	if !opts.CollapseProbes {
+		return
+	}
+
+	probes = &probeTracker{counts: make(map[probeKey]*probeCount), opts: opts}
+	go func() {
+		for range time.Tick(probeInterval) {
+			probes.flush()
+		}
+	}()
+}
+
+// Function to get the kind of probe a request line comes from, empty for real traffic
+func probeKind(line string) string {
+	match := probeRequest.FindStringSubmatch(line)
+	switch {
+	case match == nil:
+		return ""
+	case match[1] == "readyz" || match[1] == "ready":
+		return "readiness"
+	case match[1] == "livez" || match[1] == "live":
+		return "liveness"
+	default:
+		return "health"
+	}
+}
+
+// Function to count a probe request line, returning false for real traffic to display
+func (p *probeTracker) observe(src logSource, line string) bool {
+	kind := probeKind(line)
+	if kind == "" {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := probeKey{src: src, kind: kind}
+	count, ok := p.counts[key]
+	if !ok {
+		count = &probeCount{}
+		p.counts[key] = count
+	}
+	if status := probeStatus.FindStringSubmatch(line); status != nil && status[1] >= "400" {
+		count.failed++
+	} else {
+		count.ok++
+	}
+	return true
+}
+
+// Function to print and reset the probe counts of the last interval
+func (p *probeTracker) flush() {
+	p.mu.Lock()
+	counts := p.counts
+	p.counts = make(map[probeKey]*probeCount)
+	p.mu.Unlock()
+
+	keys := make([]probeKey, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+
+	timestamp := time.Now().Format(timestampFormat)
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	for _, key := range keys {
+		var prefix string
+		if showPrefix(p.opts) {
+			prefix = sourcePrefix(key.src, p.opts) + " "
+		}
+
+		results := []string{fmt.Sprintf("%d ok", counts[key].ok)}
+		if counts[key].failed > 0 {
+			results = append(results, pterm.Red(fmt.Sprintf("%d failed", counts[key].failed)))
+		}
+		fmt.Fprintf(markerOutput, "%s %s%s\n", pterm.FgDarkGray.Sprint(timestamp), prefix, pterm.FgDarkGray.Sprintf("%s probes: %s", key.kind, strings.Join(results, ", ")))
+	}
+}
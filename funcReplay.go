@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var replayCmd = &cobra.Command{
+	Use:     "replay <file>...",
+	Short:   "Render saved log files, such as the ones written by dump, like a live stream.",
+	Example: "  klog replay ./incident/shop_my-api-7d9f8/app.log -t -k timeout",
+	Args:    cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		replay(args, flags)
+	},
+}
+
+func init() {
+	replayCmd.Flags().StringVarP(&flags.Keyword, "keyword", "k", "", "Keyword for highlighting")
+	replayCmd.Flags().BoolVarP(&flags.IgnoreCase, "ignore-case", "i", false, "Highlight the keyword whatever its case")
+	replayCmd.Flags().BoolVar(&flags.KeywordRegex, "keyword-regex", false, "Match the keyword as a regex instead of literally")
+}
+
+// Function to get the source of a file from the <namespace>_<pod>/<container>.log layout of dumps
+func replaySource(path string) logSource {
+	container := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	namespace, pod, found := strings.Cut(filepath.Base(filepath.Dir(path)), "_")
+	if !found {
+		return logSource{Pod: container, Container: container}
+	}
+	return logSource{Namespace: namespace, Pod: pod, Container: container}
+}
+
+// Function to print the lines of saved files through the same rendering as pod logs
+func replay(paths []string, opts Options) {
+	initRules(opts)
+	initKeyword(opts)
+
+	// Lines of several files are told apart by their pod prefix
+	opts.AllPods = len(paths) > 1
+
+	sources := make([]logSource, len(paths))
+	for i, path := range paths {
+		sources[i] = replaySource(path)
+	}
+	assignSessionColors(sources, opts.Deterministic)
+
+	for i, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			pterm.Error.Printf("Error opening %s: %v\n", path, err)
+			os.Exit(1)
+		}
+
+		scanner := newLineScanner(file)
+		for scanner.Scan() {
+			printLogLine(sources[i], scanner.Text(), opts)
+		}
+		file.Close()
+		if err := scanner.Err(); err != nil {
+			pterm.Error.Printf("Error reading %s: %v\n", path, err)
+			os.Exit(1)
+		}
+	}
+}
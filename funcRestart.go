@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/pterm/pterm"
+)
+
+// Function to get the id of the last stopped instance of a container
+func stoppedContainerID(status *v1.ContainerStatus) string {
+	if status.State.Terminated != nil {
+		return status.State.Terminated.ContainerID
+	}
+	if status.LastTerminationState.Terminated != nil {
+		return status.LastTerminationState.Terminated.ContainerID
+	}
+	return ""
+}
+
+func printRestart(src logSource, status *v1.ContainerStatus) {
+	pterm.Warning.Printf("Container '%s' in pod '%s' restarted (restart #%d), following the new instance\n", src.Container, src.Pod, status.RestartCount)
+}
+
+// Function to tell whether the kubelet will start a stopped container again
+func willRestart(pod *v1.Pod, status *v1.ContainerStatus) bool {
+	if pod.DeletionTimestamp != nil || pod.Spec.RestartPolicy == v1.RestartPolicyNever {
+		return false
+	}
+	terminated := status.State.Terminated
+	return pod.Spec.RestartPolicy != v1.RestartPolicyOnFailure || terminated == nil || terminated.ExitCode != 0
+}
+
+// Function to wait until a stopped container runs again, false when it won't be restarted
+func waitForRestart(ctx context.Context, clientset *kubernetes.Clientset, src logSource) bool {
+	pods := clientset.CoreV1().Pods(src.Namespace)
+	pod, err := pods.Get(ctx, src.Pod, metav1.GetOptions{})
+	if err != nil {
+		return false
+	}
+	status := containerStatus(pod, src.Container)
+	if status == nil || !willRestart(pod, status) {
+		return false
+	}
+
+	stoppedID := stoppedContainerID(status)
+	if status.State.Running != nil && status.ContainerID != stoppedID {
+		printRestart(src, status)
+		return true
+	}
+	pterm.Warning.Printf("Container '%s' in pod '%s' stopped (%s), waiting for its restart\n", src.Container, src.Pod, terminationReason(pod, src.Container))
+
+	selector := fields.OneTermEqualSelector("metadata.name", src.Pod).String()
+	for ctx.Err() == nil {
+		watcher, err := pods.Watch(ctx, metav1.ListOptions{FieldSelector: selector, ResourceVersion: pod.ResourceVersion})
+		if err != nil {
+			return false
+		}
+
+		for event := range watcher.ResultChan() {
+			if event.Type == watch.Deleted {
+				watcher.Stop()
+				return false
+			}
+			updated, ok := event.Object.(*v1.Pod)
+			if !ok {
+				// Expired resource version, get the pod again below
+				break
+			}
+
+			pod = updated
+			status = containerStatus(pod, src.Container)
+			if status == nil || (status.State.Running == nil && !willRestart(pod, status)) {
+				watcher.Stop()
+				return false
+			}
+			if status.State.Running != nil && status.ContainerID != stoppedID {
+				watcher.Stop()
+				printRestart(src, status)
+				return true
+			}
+		}
+		watcher.Stop()
+
+		if pod, err = pods.Get(ctx, src.Pod, metav1.GetOptions{}); err != nil {
+			return false
+		}
+		if status = containerStatus(pod, src.Container); status != nil && status.State.Running != nil && status.ContainerID != stoppedID {
+			printRestart(src, status)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/pterm/pterm"
+)
+
+// Standard label and annotation of the git revision an image was built from
+const ociRevisionLabel = "org.opencontainers.image.revision"
+
+// How long to wait for the registry of an image
+const registryTimeout = 10 * time.Second
+
+// Media types of the image manifests and indexes read from registries
+var manifestTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+var bearerParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+var (
+	// Revision of each streamed container, resolved when the session starts
+	sourceRevisions sync.Map
+	// Revision of each image, empty when unknown, to ask registries once
+	imageRevisions sync.Map
+)
+
+// Function to tell whether the revisions of the images are needed, by --revision or a {revision} template
+func revisionsNeeded(opts Options) bool {
+	if opts.Revision || strings.Contains(opts.CodeURL, "{revision}") {
+		return true
+	}
+	for _, dashboard := range opts.Dashboards {
+		if strings.Contains(dashboard, "{revision}") {
+			return true
+		}
+	}
+	return false
+}
+
+// Function to resolve the revision of the streamed containers and print it in the stream header
+func resolveRevisions(ctx context.Context, clientset *kubernetes.Clientset, sources []logSource, opts Options) {
+	if !revisionsNeeded(opts) || clientset == nil {
+		return
+	}
+
+	for _, src := range sources {
+		pod, err := clientset.CoreV1().Pods(src.Namespace).Get(ctx, src.Pod, metav1.GetOptions{})
+		if err != nil {
+			continue
+		}
+		image := containerImage(pod, src.Container)
+		revision := podRevision(ctx, pod, src.Container, image)
+		if revision == "" {
+			pterm.Info.Printf("Container '%s' of pod '%s' runs %s, revision unknown\n", src.Container, src.Pod, image)
+			continue
+		}
+		sourceRevisions.Store(src, revision)
+		pterm.Info.Printf("Container '%s' of pod '%s' runs %s, revision %s\n", src.Container, src.Pod, image, revision)
+	}
+}
+
+// Function to get the revision of a stream for templates, HEAD when unknown
+func revisionOf(src logSource) string {
+	if revision, ok := sourceRevisions.Load(src); ok {
+		return revision.(string)
+	}
+	return "HEAD"
+}
+
+// Function to get the image of a container, by digest when the kubelet reports it
+func containerImage(pod *v1.Pod, container string) string {
+	if status := containerStatus(pod, container); status != nil && strings.Contains(status.ImageID, "@sha256:") {
+		return strings.TrimPrefix(status.ImageID, "docker-pullable://")
+	}
+	for _, c := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+		if c.Name == container {
+			return c.Image
+		}
+	}
+	for _, c := range pod.Spec.EphemeralContainers {
+		if c.Name == container {
+			return c.Image
+		}
+	}
+	return ""
+}
+
+// Function to get the revision of a container from the annotations of its pod, per container
+// with <label>/<container>, or else from the labels of its image
+func podRevision(ctx context.Context, pod *v1.Pod, container string, image string) string {
+	if revision := pod.Annotations[ociRevisionLabel+"/"+container]; revision != "" {
+		return revision
+	}
+	if revision := pod.Annotations[ociRevisionLabel]; revision != "" {
+		return revision
+	}
+	return imageRevision(ctx, image)
+}
+
+// Function to read the revision label of an image from its registry, anonymously and once per image
+func imageRevision(ctx context.Context, image string) string {
+	if image == "" {
+		return ""
+	}
+	if revision, ok := imageRevisions.Load(image); ok {
+		return revision.(string)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, registryTimeout)
+	defer cancel()
+	labels, err := imageLabels(ctx, image)
+	if err != nil {
+		pterm.Warning.Printf("Unable to read the labels of image %s: %v\n", image, err)
+	}
+	imageRevisions.Store(image, labels[ociRevisionLabel])
+	return labels[ociRevisionLabel]
+}
+
+// Function to read the labels of the config of an image with the registry API
+func imageLabels(ctx context.Context, image string) (map[string]string, error) {
+	registry, repository, reference := parseImage(image)
+	base := fmt.Sprintf("https://%s/v2/%s", registry, repository)
+	token := ""
+
+	var manifest struct {
+		MediaType string `json:"mediaType"`
+		Config    struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+		Manifests []struct {
+			Digest   string `json:"digest"`
+			Platform struct {
+				OS           string `json:"os"`
+				Architecture string `json:"architecture"`
+			} `json:"platform"`
+		} `json:"manifests"`
+	}
+	if err := registryGet(ctx, base+"/manifests/"+reference, &token, &manifest); err != nil {
+		return nil, err
+	}
+
+	// A multi-platform index points to a manifest per platform, their labels are the same
+	if len(manifest.Manifests) > 0 {
+		digest := manifest.Manifests[0].Digest
+		for _, m := range manifest.Manifests {
+			if m.Platform.OS == "linux" && m.Platform.Architecture == "amd64" {
+				digest = m.Digest
+				break
+			}
+		}
+		if err := registryGet(ctx, base+"/manifests/"+digest, &token, &manifest); err != nil {
+			return nil, err
+		}
+	}
+
+	var config struct {
+		Config struct {
+			Labels map[string]string `json:"Labels"`
+		} `json:"config"`
+	}
+	if err := registryGet(ctx, base+"/blobs/"+manifest.Config.Digest, &token, &config); err != nil {
+		return nil, err
+	}
+	return config.Config.Labels, nil
+}
+
+// Function to split an image into its registry, repository and tag or digest, as Docker does
+func parseImage(image string) (string, string, string) {
+	registry := "registry-1.docker.io"
+	if first, rest, found := strings.Cut(image, "/"); found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		registry, image = first, rest
+	} else if !found {
+		image = "library/" + image
+	}
+	if registry == "docker.io" {
+		registry = "registry-1.docker.io"
+	}
+
+	if repository, digest, found := strings.Cut(image, "@"); found {
+		// The digest wins over a tag given with it
+		if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+			repository = repository[:i]
+		}
+		return registry, repository, digest
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return registry, image[:i], image[i+1:]
+	}
+	return registry, image, "latest"
+}
+
+// Function to get a JSON document of a registry, asking for an anonymous token when challenged
+func registryGet(ctx context.Context, url string, token *string, target interface{}) error {
+	for attempt := 0; ; attempt++ {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Accept", strings.Join(manifestTypes, ", "))
+		if *token != "" {
+			request.Header.Set("Authorization", "Bearer "+*token)
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			return err
+		}
+
+		if response.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			challenge := response.Header.Get("WWW-Authenticate")
+			response.Body.Close()
+			if *token, err = registryToken(ctx, challenge); err != nil {
+				return err
+			}
+			continue
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s: %s", url, response.Status)
+		}
+		return json.NewDecoder(response.Body).Decode(target)
+	}
+}
+
+// Function to get an anonymous pull token from the realm of a Bearer challenge
+func registryToken(ctx context.Context, challenge string) (string, error) {
+	if !strings.HasPrefix(challenge, "Bearer ") {
+		return "", fmt.Errorf("registry requires credentials")
+	}
+	params := make(map[string]string)
+	for _, match := range bearerParam.FindAllStringSubmatch(challenge, -1) {
+		params[match[1]] = match[2]
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, params["realm"], nil)
+	if err != nil {
+		return "", err
+	}
+	query := request.URL.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	request.URL.RawQuery = query.Encode()
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry token: %s", response.Status)
+	}
+	var answer struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&answer); err != nil {
+		return "", err
+	}
+	if answer.Token == "" {
+		return answer.AccessToken, nil
+	}
+	return answer.Token, nil
+}
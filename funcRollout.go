@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/pterm/pterm"
+)
+
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+// Function to get the Deployment owning a pod through its ReplicaSet
+func podDeployment(ctx context.Context, clientset *kubernetes.Clientset, src logSource) (*appsv1.Deployment, error) {
+	pod, err := clientset.CoreV1().Pods(src.Namespace).Get(ctx, src.Pod, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, podOwner := range pod.OwnerReferences {
+		if podOwner.Kind != "ReplicaSet" {
+			continue
+		}
+		replicaSet, err := clientset.AppsV1().ReplicaSets(src.Namespace).Get(ctx, podOwner.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, owner := range replicaSet.OwnerReferences {
+			if owner.Kind == "Deployment" {
+				return clientset.AppsV1().Deployments(src.Namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+			}
+		}
+	}
+	return nil, nil
+}
+
+// Function to insert a separator in the stream whenever a Deployment of the streamed pods rolls out
+func watchRollouts(ctx context.Context, clientset *kubernetes.Clientset, sources []logSource, opts Options) {
+	if !opts.Rollouts || clientset == nil {
+		return
+	}
+
+	watched := make(map[string]bool)
+	for _, src := range sources {
+		deployment, err := podDeployment(ctx, clientset, src)
+		if err != nil || deployment == nil {
+			continue
+		}
+		key := deployment.Namespace + "/" + deployment.Name
+		if watched[key] {
+			continue
+		}
+		watched[key] = true
+		go watchDeploymentRevisions(ctx, clientset, deployment, revisionsNeeded(opts))
+	}
+}
+
+func watchDeploymentRevisions(ctx context.Context, clientset *kubernetes.Clientset, deployment *appsv1.Deployment, withRevision bool) {
+	selector := metav1.FormatLabelSelector(deployment.Spec.Selector)
+	replicaSets := clientset.AppsV1().ReplicaSets(deployment.Namespace)
+	lastRevision := -1
+
+	for ctx.Err() == nil {
+		list, err := replicaSets.List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		for _, replicaSet := range list.Items {
+			revision := replicaSetRevision(&replicaSet)
+			if lastRevision >= 0 && revision > lastRevision {
+				printRollout(ctx, deployment.Name, &replicaSet, withRevision)
+			}
+			if revision > lastRevision {
+				lastRevision = revision
+			}
+		}
+
+		watcher, err := replicaSets.Watch(ctx, metav1.ListOptions{LabelSelector: selector, ResourceVersion: list.ResourceVersion})
+		if err != nil {
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		for event := range watcher.ResultChan() {
+			replicaSet, ok := event.Object.(*appsv1.ReplicaSet)
+			if !ok || (event.Type != watch.Added && event.Type != watch.Modified) {
+				continue
+			}
+			if revision := replicaSetRevision(replicaSet); revision > lastRevision {
+				lastRevision = revision
+				printRollout(ctx, deployment.Name, replicaSet, withRevision)
+			}
+		}
+		watcher.Stop()
+	}
+}
+
+func replicaSetRevision(replicaSet *appsv1.ReplicaSet) int {
+	revision, err := strconv.Atoi(replicaSet.Annotations[revisionAnnotation])
+	if err != nil {
+		return -1
+	}
+	return revision
+}
+
+func printRollout(ctx context.Context, deployment string, replicaSet *appsv1.ReplicaSet, withRevision bool) {
+	var images []string
+	for _, container := range replicaSet.Spec.Template.Spec.Containers {
+		images = append(images, container.Image)
+	}
+
+	// Git revision of the first container of the new pods
+	var built string
+	if container := replicaSet.Spec.Template.Spec.Containers; withRevision && len(container) > 0 {
+		pod := &v1.Pod{ObjectMeta: replicaSet.Spec.Template.ObjectMeta, Spec: replicaSet.Spec.Template.Spec}
+		if revision := podRevision(ctx, pod, container[0].Name, container[0].Image); revision != "" {
+			built = ", built from " + revision
+		}
+	}
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	fmt.Fprintln(markerOutput, pterm.FgLightMagenta.Sprintf("──── deployment %s revision %d rolled out (image %s%s) ────",
+		deployment, replicaSetRevision(replicaSet), strings.Join(images, ", "), built))
+}
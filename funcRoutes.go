@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// Lines waiting to be posted to a webhook before new ones are dropped
+const webhookQueueSize = 256
+
+// Route of the config: send the lines of the levels (every level when empty) to the sinks,
+// 'terminal', 'drop', 'file:<path>' or 'webhook:<url>'
+type route struct {
+	Levels []string `json:"levels,omitempty"`
+	Sinks  []string `json:"sinks"`
+}
+
+// Sinks of the routes, opened when the session starts
+type lineRouter struct {
+	mu       sync.Mutex
+	routes   []route
+	files    map[string]*os.File
+	webhooks map[string]chan logRecord
+}
+
+var router *lineRouter
+
+// Function to check the levels and sinks of a route
+func validateRoute(r route) error {
+	for _, level := range r.Levels {
+		if !slices.Contains(ruleLevels, level) {
+			return fmt.Errorf("invalid route level '%s', use one of %s", level, strings.Join(ruleLevels, ", "))
+		}
+	}
+	if len(r.Sinks) == 0 {
+		return fmt.Errorf("route of levels '%s' has no sink", strings.Join(r.Levels, " "))
+	}
+	for _, sink := range r.Sinks {
+		kind, target, _ := strings.Cut(sink, ":")
+		switch {
+		case sink == "terminal" || sink == "drop":
+		case (kind == "file" || kind == "webhook") && target != "":
+		default:
+			return fmt.Errorf("invalid sink '%s', use terminal, drop, file:<path> or webhook:<url>", sink)
+		}
+	}
+	return nil
+}
+
+// Function to open the file and webhook sinks of the configured routes
+func startRouting() {
+	if len(activeRules.routes) == 0 {
+		return
+	}
+
+	router = &lineRouter{
+		routes:   activeRules.routes,
+		files:    make(map[string]*os.File),
+		webhooks: make(map[string]chan logRecord),
+	}
+	for _, r := range activeRules.routes {
+		for _, sink := range r.Sinks {
+			kind, target, _ := strings.Cut(sink, ":")
+			switch {
+			case kind == "file" && router.files[target] == nil:
+				file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+				if err != nil {
+					pterm.Error.Printf("Error opening route file: %v\n", err)
+					os.Exit(1)
+				}
+				router.files[target] = file
+			case kind == "webhook" && router.webhooks[target] == nil:
+				queue := make(chan logRecord, webhookQueueSize)
+				router.webhooks[target] = queue
+				go postWebhook(target, queue)
+			}
+		}
+	}
+}
+
+// Function to send a line to the sinks of the first route of its level, true when it goes to the terminal
+func (r *lineRouter) dispatch(line printedLine) bool {
+	for _, rt := range r.routes {
+		if len(rt.Levels) > 0 && !slices.Contains(rt.Levels, line.level) {
+			continue
+		}
+
+		terminal := false
+		for _, sink := range rt.Sinks {
+			kind, target, _ := strings.Cut(sink, ":")
+			switch kind {
+			case "terminal":
+				terminal = true
+			case "file":
+				r.mu.Lock()
+				_, err := fmt.Fprintf(r.files[target], "%s [%s/%s/%s] %s\n", line.time.Format(time.RFC3339Nano), line.src.Namespace, line.src.Pod, line.src.Container, line.line)
+				r.mu.Unlock()
+				if err != nil {
+					pterm.Warning.Printf("Error writing route file %s: %v\n", target, err)
+				}
+			case "webhook":
+				// Drop the line rather than blocking the log stream on a slow webhook
+				select {
+				case r.webhooks[target] <- line.record():
+				default:
+				}
+			}
+		}
+		return terminal
+	}
+	// Lines of no route are displayed
+	return true
+}
+
+// Function to post the queued lines to a webhook, one JSON record per request
+func postWebhook(url string, queue chan logRecord) {
+	for record := range queue {
+		data, err := json.Marshal(record)
+		if err != nil {
+			continue
+		}
+		response, err := http.Post(url, "application/json", bytes.NewReader(data))
+		if err != nil {
+			pterm.Warning.Printf("Error posting to webhook %s: %v\n", url, err)
+			continue
+		}
+		response.Body.Close()
+		if response.StatusCode >= 300 {
+			pterm.Warning.Printf("Webhook %s answered %s\n", url, response.Status)
+		}
+	}
+}
@@ -0,0 +1,456 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// Levels a level rule can set
+var ruleLevels = []string{"error", "warning", "panic", "debug", "info"}
+
+// Rule of the rules file: highlight or mute the lines matching pattern, give them a level,
+// replace the matches in sanitized exports, read the application timestamp they match with layout,
+// or unwrap the original line from the JSON field of a forwarder
+type rule struct {
+	Kind        string `json:"kind"`
+	Pattern     string `json:"pattern"`
+	Level       string `json:"level,omitempty"`
+	Replacement string `json:"replacement,omitempty"`
+	Layout      string `json:"layout,omitempty"`
+	Field       string `json:"field,omitempty"`
+}
+
+type ruleFile struct {
+	Rules      []rule          `json:"rules"`
+	Namespaces *namespaceRules `json:"namespaces,omitempty"`
+	Routes     []route         `json:"routes,omitempty"`
+	LevelKeys  []string        `json:"levelKeys,omitempty"`
+	Peers      []peerRule      `json:"peers,omitempty"`
+}
+
+// Rules compiled for the rendering
+type compiledRules struct {
+	highlight []string
+	mute      []*regexp.Regexp
+	levels    []compiledRule
+	redact    []compiledRule
+	times     []compiledRule
+	unwraps   []compiledRule
+	routes    []route
+	peers     []compiledPeerRule
+	// Keys of the JSON and logfmt fields holding the level, with the --level-key and levelKeys aliases
+	levelKeys []string
+
+	allowNamespaces []*regexp.Regexp
+	denyNamespaces  []*regexp.Regexp
+}
+
+type compiledRule struct {
+	re *regexp.Regexp
+	rule
+}
+
+var (
+	activeRules = &compiledRules{levelKeys: []string{"level"}}
+	rulesLoaded bool
+)
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Manage the highlight, mute and level rules applied to log lines.",
+}
+
+var rulesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the configured rules.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		listRules(flags)
+	},
+}
+
+var rulesAddCmd = &cobra.Command{
+	Use:     "add highlight|mute|level|redact|timestamp|unwrap <pattern> [level|replacement|layout|field]",
+	Short:   "Add a rule to the rules file.",
+	Example: "  klog rules add mute 'GET /healthz'\n  klog rules add level 'deprecated' warning\n  klog rules add redact 'token=\\S+' 'token=<redacted>'\n  klog rules add timestamp '^\\[([^]]+)\\]' '02/Jan/2006:15:04:05 -0700'\n  klog rules add unwrap '^\\{\"log\":' log",
+	Args:    cobra.RangeArgs(2, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		newRule := rule{Kind: args[0], Pattern: args[1]}
+		if len(args) == 3 {
+			switch newRule.Kind {
+			case "redact":
+				newRule.Replacement = args[2]
+			case "timestamp":
+				newRule.Layout = args[2]
+			case "unwrap":
+				newRule.Field = args[2]
+			default:
+				newRule.Level = args[2]
+			}
+		}
+		addRule(newRule, flags)
+	},
+}
+
+var rulesTestCmd = &cobra.Command{
+	Use:     "test",
+	Short:   "Show how the rules classify the lines of a sample file.",
+	Example: "  klog rules test --file sample.log --rules ./draft-rules.yaml",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		testRules(flags)
+	},
+}
+
+func init() {
+	rulesCmd.AddCommand(rulesListCmd, rulesAddCmd, rulesTestCmd)
+	rulesTestCmd.Flags().StringVar(&flags.RulesSample, "file", "", "Sample log file to classify")
+	rulesTestCmd.Flags().StringVarP(&flags.Keyword, "keyword", "k", "", "Keyword for highlighting")
+	rulesTestCmd.Flags().BoolVarP(&flags.IgnoreCase, "ignore-case", "i", false, "Highlight the keyword whatever its case")
+	rulesTestCmd.Flags().BoolVar(&flags.KeywordRegex, "keyword-regex", false, "Match the keyword as a regex instead of literally")
+	_ = rulesTestCmd.MarkFlagRequired("file")
+}
+
+// Function to get the rules file, --rules or the one of the user configuration
+func rulesPath(opts Options) string {
+	if opts.Rules != "" {
+		return opts.Rules
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "klog", "rules.yaml")
+}
+
+// Function to read the rules file, a missing file has no rules
+func loadRules(path string) (ruleFile, error) {
+	var rules ruleFile
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return rules, nil
+	}
+	if err != nil {
+		return rules, err
+	}
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return rules, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// Function to check a rule and compile its pattern
+func validateRule(r rule) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern '%s': %w", r.Pattern, err)
+	}
+	switch r.Kind {
+	case "highlight", "mute", "redact", "unwrap":
+	case "timestamp":
+		if r.Layout == "" {
+			return nil, fmt.Errorf("missing layout of timestamp rule '%s', e.g. '2006-01-02 15:04:05'", r.Pattern)
+		}
+	case "level":
+		for _, level := range ruleLevels {
+			if r.Level == level {
+				return re, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid level '%s', use one of %s", r.Level, strings.Join(ruleLevels, ", "))
+	default:
+		return nil, fmt.Errorf("invalid kind '%s', use highlight, mute, level, redact, timestamp or unwrap", r.Kind)
+	}
+	return re, nil
+}
+
+func compileRules(rules ruleFile) (*compiledRules, error) {
+	compiled := &compiledRules{levelKeys: append([]string{"level"}, rules.LevelKeys...)}
+	for _, r := range rules.Rules {
+		re, err := validateRule(r)
+		if err != nil {
+			return nil, err
+		}
+		switch r.Kind {
+		case "highlight":
+			compiled.highlight = append(compiled.highlight, r.Pattern)
+		case "mute":
+			compiled.mute = append(compiled.mute, re)
+		case "level":
+			compiled.levels = append(compiled.levels, compiledRule{re: re, rule: r})
+		case "redact":
+			compiled.redact = append(compiled.redact, compiledRule{re: re, rule: r})
+		case "timestamp":
+			compiled.times = append(compiled.times, compiledRule{re: re, rule: r})
+		case "unwrap":
+			compiled.unwraps = append(compiled.unwraps, compiledRule{re: re, rule: r})
+		}
+	}
+
+	for _, r := range rules.Routes {
+		if err := validateRoute(r); err != nil {
+			return nil, err
+		}
+	}
+	compiled.routes = rules.Routes
+
+	for _, r := range rules.Peers {
+		peer, err := compilePeerRule(r)
+		if err != nil {
+			return nil, err
+		}
+		compiled.peers = append(compiled.peers, peer)
+	}
+
+	if rules.Namespaces != nil {
+		var err error
+		if compiled.allowNamespaces, err = compileNamespacePatterns(rules.Namespaces.Allow); err != nil {
+			return nil, err
+		}
+		if compiled.denyNamespaces, err = compileNamespacePatterns(rules.Namespaces.Deny); err != nil {
+			return nil, err
+		}
+	}
+	return compiled, nil
+}
+
+// Function to get the rules of the shared --config followed by the local ones
+func loadAllRules(opts Options) (ruleFile, ruleFile, error) {
+	shared, err := loadSharedConfig(opts)
+	if err != nil {
+		return shared, ruleFile{}, err
+	}
+	local, err := loadRules(rulesPath(opts))
+	return shared, local, err
+}
+
+// Function to load the rules applied to the streamed lines
+func initRules(opts Options) {
+	shared, local, err := loadAllRules(opts)
+	if err == nil {
+		activeRules, err = compileRules(ruleFile{
+			Rules:      append(shared.Rules, local.Rules...),
+			Namespaces: mergeNamespaceRules(shared.Namespaces, local.Namespaces),
+			Routes:     append(shared.Routes, local.Routes...),
+			Peers:      append(shared.Peers, local.Peers...),
+			LevelKeys:  slices.Concat(opts.LevelKeys, shared.LevelKeys, local.LevelKeys),
+		})
+	}
+	if err != nil {
+		pterm.Error.Printf("Invalid rules: %v\n", err)
+		os.Exit(1)
+	}
+	rulesLoaded = true
+}
+
+// Function to classify a line with the level rules first, then the built-in keywords
+func (c *compiledRules) classify(line string) (string, string, map[string]interface{}) {
+	level, rule, fields := classifyLine(line, c.levelKeys)
+	for _, r := range c.levels {
+		if r.re.MatchString(line) {
+			return r.Level, fmt.Sprintf("level rule %q", r.Pattern), fields
+		}
+	}
+	return level, rule, fields
+}
+
+func (c *compiledRules) muted(line string) bool {
+	for _, re := range c.mute {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// Function to read the time an application logged a line with the timestamp rules, the first
+// group of the pattern or else the whole match being parsed with the layout of the rule
+func (c *compiledRules) appTime(line string) (time.Time, bool) {
+	for _, r := range c.times {
+		match := r.re.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		text := match[0]
+		if len(match) > 1 {
+			text = match[1]
+		}
+		if t, err := time.ParseInLocation(r.Layout, text, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Function to extract the original line from the JSON wrapper of a forwarder with the unwrap rules,
+// the field being a dotted path defaulting to 'log'
+func (c *compiledRules) unwrap(line string) (string, bool) {
+	for _, r := range c.unwraps {
+		if !r.re.MatchString(line) {
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(line), &value); err != nil {
+			continue
+		}
+		field := r.Field
+		if field == "" {
+			field = "log"
+		}
+		for _, key := range strings.Split(field, ".") {
+			object, _ := value.(map[string]interface{})
+			value = object[key]
+		}
+		if inner, ok := value.(string); ok {
+			return strings.TrimRight(inner, "\r\n"), true
+		}
+	}
+	return line, false
+}
+
+// Function to get the time an application logged a line, from the timestamp rules or else
+// from the time field of a line unwrapped from a forwarder
+func (c *compiledRules) eventTime(line string, unwrapped bool) (time.Time, bool) {
+	if t, ok := c.appTime(line); ok || !unwrapped {
+		return t, ok
+	}
+	return innerTime(line)
+}
+
+// Function to read the RFC3339 time field of an unwrapped JSON line
+func innerTime(line string) (time.Time, bool) {
+	var logEntry map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &logEntry); err != nil {
+		return time.Time{}, false
+	}
+	for _, key := range []string{"time", "timestamp", "ts", "@timestamp"} {
+		if text, ok := logEntry[key].(string); ok {
+			if t, err := time.Parse(time.RFC3339Nano, text); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// Function to add the highlight rules to the keyword
+func (c *compiledRules) highlighted(keyword string) string {
+	patterns := c.highlight
+	if keyword != "" {
+		patterns = append([]string{keyword}, patterns...)
+	}
+	return strings.Join(patterns, "|")
+}
+
+func listRules(opts Options) {
+	path := rulesPath(opts)
+	shared, local, err := loadAllRules(opts)
+	if err != nil {
+		pterm.Error.Printf("Error reading rules: %v\n", err)
+		os.Exit(1)
+	}
+	if namespaces := mergeNamespaceRules(shared.Namespaces, local.Namespaces); len(namespaces.Allow)+len(namespaces.Deny) > 0 {
+		pterm.Info.Printf("Protected namespaces: allow %s, deny %s\n", strings.Join(namespaces.Allow, " "), strings.Join(namespaces.Deny, " "))
+	}
+	for _, r := range append(shared.Routes, local.Routes...) {
+		levels := strings.Join(r.Levels, " ")
+		if levels == "" {
+			levels = "every level"
+		}
+		pterm.Info.Printf("Route: %s to %s\n", levels, strings.Join(r.Sinks, " "))
+	}
+	for _, r := range append(shared.Peers, local.Peers...) {
+		pterm.Info.Printf("Peers: %s with %s\n", r.Pod, strings.Join(r.With, " "))
+	}
+	if keys := append(shared.LevelKeys, local.LevelKeys...); len(keys) > 0 {
+		pterm.Info.Printf("Level keys: level %s\n", strings.Join(keys, " "))
+	}
+	if len(shared.Rules)+len(local.Rules) == 0 {
+		pterm.Info.Printf("No rule in %s\n", path)
+		return
+	}
+
+	table := pterm.TableData{{"Source", "Kind", "Pattern", "Level", "Replacement", "Layout", "Field"}}
+	for _, r := range shared.Rules {
+		table = append(table, []string{opts.Config, r.Kind, r.Pattern, r.Level, r.Replacement, r.Layout, r.Field})
+	}
+	for _, r := range local.Rules {
+		table = append(table, []string{path, r.Kind, r.Pattern, r.Level, r.Replacement, r.Layout, r.Field})
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(table).Render()
+}
+
+func addRule(newRule rule, opts Options) {
+	if _, err := validateRule(newRule); err != nil {
+		pterm.Error.Printf("Invalid rule: %v\n", err)
+		os.Exit(1)
+	}
+
+	path := rulesPath(opts)
+	rules, err := loadRules(path)
+	if err != nil {
+		pterm.Error.Printf("Error reading rules: %v\n", err)
+		os.Exit(1)
+	}
+	rules.Rules = append(rules.Rules, newRule)
+
+	data, err := yaml.Marshal(rules)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(path, data, 0o644)
+	}
+	if err != nil {
+		pterm.Error.Printf("Error saving rules: %v\n", err)
+		os.Exit(1)
+	}
+	pterm.Success.Printf("Rule added to %s\n", path)
+}
+
+// Function to print the level, deciding rule and rendering of every line of the sample file
+func testRules(opts Options) {
+	initRules(opts)
+	// The keyword highlighted as in a session, literally unless --keyword-regex
+	initKeyword(opts)
+	keyword := currentKeyword()
+
+	file, err := os.Open(opts.RulesSample)
+	if err != nil {
+		pterm.Error.Printf("Error opening %s: %v\n", opts.RulesSample, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	counts := make(map[string]int)
+	scanner := newLineScanner(file)
+	for scanner.Scan() {
+		line, _ := activeRules.unwrap(scanner.Text())
+		if activeRules.muted(line) {
+			counts["muted"]++
+			fmt.Printf("%-8s %-40s %s\n", "muted", "", pterm.FgDarkGray.Sprint(line))
+			continue
+		}
+		level, rule, _ := activeRules.classify(line)
+		counts[level]++
+		fmt.Printf("%-8s %-40s %s\n", level, rule, printedLine{line: line, level: level, opts: opts}.render(keyword))
+	}
+	if err := scanner.Err(); err != nil {
+		pterm.Error.Printf("Error reading %s: %v\n", opts.RulesSample, err)
+		os.Exit(1)
+	}
+
+	pterm.Info.Printf("%d error, %d warning, %d panic, %d debug, %d info, %d muted\n",
+		counts["error"], counts["warning"], counts["panic"], counts["debug"], counts["info"], counts["muted"])
+}
@@ -0,0 +1,7 @@
+package main
+
+import "testing"
+
+func TestUnwrapGolden(t *testing.T) {
+	checkFlagGolden(t, "unwrap", Options{}, ruleFile{Rules: []rule{{Kind: "unwrap", Pattern: `^\{"log":`}}})
+}
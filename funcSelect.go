@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/pterm/pterm"
+)
+
+// Function to create the Kubernetes client of the kubeconfig context in use
+func newClientset(opts Options) *kubernetes.Clientset {
+	clientset, err := kubernetes.NewForConfig(loadKubeConfig(opts))
+	if err != nil {
+		pterm.Error.Printf("Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+	return clientset
+}
+
+// Delay between two searches of the pods with --if-none wait
+const ifNoneWaitDelay = 2 * time.Second
+
+// Containers never selected with --exclude-container
+var excludedContainerPatterns []*regexp.Regexp
+
+// Containers selected with -c, matched by name or regex
+var containerPattern *regexp.Regexp
+
+// Function to find the pods matching the name regex or workload and the selectors, after checking the access to their logs
+func findPods(ctx context.Context, clientset *kubernetes.Clientset, pattern string, opts Options) []v1.Pod {
+	// Commands without rendering still need the protected namespaces of the config
+	if !rulesLoaded {
+		initRules(opts)
+	}
+
+	switch opts.IfNone {
+	case "wait", "error", "ok":
+	default:
+		pterm.Error.Printf("Invalid --if-none '%s', use wait, error or ok\n", opts.IfNone)
+		os.Exit(1)
+	}
+	if err := initContainerExcludes(opts); err != nil {
+		pterm.Error.Printf("Invalid --exclude-container: %v\n", err)
+		os.Exit(1)
+	}
+	if opts.Container != "" {
+		re, err := regexp.Compile(opts.Container)
+		if err != nil {
+			pterm.Error.Printf("Invalid --container: %v\n", err)
+			os.Exit(1)
+		}
+		containerPattern = re
+	}
+
+	spinner := startProgress("Initialization in progress", opts)
+
+	namespaces := searchNamespaces(opts)
+
+	if err := checkPermissions(ctx, clientset, namespaces, requiredAccess...); err != nil {
+		spinner.Fail("Initialization failed")
+		pterm.Error.Printf("Error checking permissions: %v\n", err)
+		os.Exit(1)
+	}
+	// Missing events only leave them out of the dump, the logs are still saved
+	if opts.Dump != "" {
+		if err := checkPermissions(ctx, clientset, namespaces, eventsAccess); err != nil {
+			pterm.Warning.Printf("Events won't be saved: %v\n", err)
+		}
+	}
+
+	noMatch := "No pod found with name: " + pattern
+	if opts.Selector != "" || opts.FieldSelector != "" {
+		noMatch += " and selectors: " + strings.Trim(opts.Selector+" "+opts.FieldSelector, " ")
+	}
+
+	noCache, waiting := opts.NoCache, false
+	var matchedPods []v1.Pod
+	for {
+		var allPods []v1.Pod
+		listOptions := metav1.ListOptions{LabelSelector: opts.Selector, FieldSelector: opts.FieldSelector}
+		for _, namespace := range namespaces {
+			pods, err := listPods(ctx, clientset, currentContext(opts), namespace, listOptions, noCache)
+			if err != nil {
+				spinner.Fail("Initialization failed")
+				pterm.Error.Printf("Error fetching pods: %v\n", err)
+				os.Exit(1)
+			}
+			allPods = append(allPods, pods.Items...)
+		}
+
+		var err error
+		matchedPods, err = matchTarget(ctx, clientset, allPods, pattern, namespaces, opts)
+		if err != nil {
+			spinner.Fail("Initialization failed")
+			pterm.Error.Printf("Error matching pods: %v\n", err)
+			os.Exit(1)
+		}
+		if len(matchedPods) > 0 {
+			break
+		}
+
+		switch opts.IfNone {
+		case "wait":
+			// The cached list can't show the pod we wait for
+			if !waiting {
+				spinner.UpdateText(noMatch + ", waiting for one")
+			}
+			noCache, waiting = true, true
+			time.Sleep(ifNoneWaitDelay)
+			continue
+		case "ok":
+			spinner.Success(noMatch + ", nothing to do")
+			os.Exit(0)
+		}
+		spinner.Fail("Initialization failed")
+		pterm.Error.Println(noMatch)
+		os.Exit(1)
+	}
+
+	spinner.Success("Initialization success")
+	return guardNamespaces(matchedPods, opts)
+}
+
+// Function to get the namespaces searched, the empty one listing all of them
+func searchNamespaces(opts Options) []string {
+	if len(opts.Namespaces) == 0 {
+		return []string{""}
+	}
+	return opts.Namespaces
+}
+
+// Function to keep the pods matching the name regex, or owned by a <kind>/<name> workload, without the excluded ones
+func matchTarget(ctx context.Context, clientset *kubernetes.Clientset, pods []v1.Pod, pattern string, namespaces []string, opts Options) ([]v1.Pod, error) {
+	// A workload is resolved through the owners of the pods rather than their names
+	matchedPods := matchPods(pods, pattern)
+	if kind, name, ok := parseWorkload(pattern); ok {
+		var err error
+		matchedPods, err = workloadPods(ctx, clientset, pods, namespaces, kind, name)
+		if err != nil {
+			return nil, fmt.Errorf("resolving %s '%s': %w", strings.ToLower(kind), name, err)
+		}
+	}
+	return excludePods(matchedPods, opts.ExcludePods), nil
+}
+
+// Function to choose one of the matched pods, the one named exactly like the pattern if any
+func choosePod(ctx context.Context, clientset *kubernetes.Clientset, pods []v1.Pod, pattern string, opts Options) *v1.Pod {
+	// Pods named exactly like the pattern win, there may be one per namespace
+	candidates := pods
+	var exact []v1.Pod
+	for _, p := range pods {
+		if p.Name == pattern {
+			exact = append(exact, p)
+		}
+	}
+	if len(exact) > 0 {
+		candidates = exact
+	}
+
+	var chosen v1.Pod
+	switch {
+	case len(candidates) == 1:
+		chosen = candidates[0]
+	case opts.Latest:
+		chosen = latestPod(candidates)
+	case opts.NonInteractive:
+		pterm.Error.Printf("%d pods match '%s', use -a, --latest, -n or the exact pod name\n", len(candidates), pattern)
+		os.Exit(1)
+	default:
+		chosen = selectPod(candidates, showNamespaces(opts))
+	}
+
+	// The listing may come from the cache, get the current state of the pod
+	podInfo, err := clientset.CoreV1().Pods(chosen.Namespace).Get(ctx, chosen.Name, metav1.GetOptions{})
+	if err != nil {
+		pterm.Error.Printf("Error fetching pod information: %v\n", err)
+		os.Exit(1)
+	}
+	return podInfo
+}
+
+// Function to get the container of a pod from -c, or by asking when the pod has several
+func chooseContainer(pod *v1.Pod, opts Options) string {
+	containers := selectableContainers(*pod, opts)
+	if opts.Container != "" {
+		containers = matchContainers(*pod, opts)
+		if len(containers) == 0 {
+			pterm.Error.Printf("No container of pod '%s' matches: %s\n", pod.Name, opts.Container)
+			os.Exit(1)
+		}
+	}
+
+	if len(containers) > 1 && opts.NonInteractive {
+		names := make([]string, len(containers))
+		for i, container := range containers {
+			names[i] = container.Name
+		}
+		pterm.Error.Printf("Pod '%s' has %d matching containers (%s), use -c\n", pod.Name, len(names), strings.Join(names, ", "))
+		os.Exit(1)
+	}
+	return selectContainer(containers)
+}
+
+// Function to get the containers of a pod, after its init containers with --init-containers,
+// followed by the ephemeral containers added by kubectl debug, without the --exclude-container ones
+func selectableContainers(pod v1.Pod, opts Options) []v1.Container {
+	var all []v1.Container
+	if opts.InitContainers {
+		all = append(all, pod.Spec.InitContainers...)
+	}
+	all = append(all, pod.Spec.Containers...)
+	for _, ephemeral := range pod.Spec.EphemeralContainers {
+		all = append(all, v1.Container(ephemeral.EphemeralContainerCommon))
+	}
+
+	var containers []v1.Container
+	for _, container := range all {
+		if !excludedContainer(container.Name) {
+			containers = append(containers, container)
+		}
+	}
+	return containers
+}
+
+// Function to compile the --exclude-container regexes
+func initContainerExcludes(opts Options) error {
+	excludedContainerPatterns = nil
+	for _, pattern := range opts.ExcludeContainers {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		excludedContainerPatterns = append(excludedContainerPatterns, re)
+	}
+	return nil
+}
+
+// Function to tell whether a container matches one of the --exclude-container regexes, e.g. a mesh sidecar
+func excludedContainer(name string) bool {
+	for _, re := range excludedContainerPatterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Function to get the containers of a pod matching the -c regex, the one named exactly like it if any,
+// init containers only by their exact name unless they are selectable
+func matchContainers(pod v1.Pod, opts Options) []v1.Container {
+	var matched []v1.Container
+	for _, containers := range [][]v1.Container{pod.Spec.Containers, pod.Spec.InitContainers} {
+		for _, container := range containers {
+			if container.Name == opts.Container {
+				return []v1.Container{container}
+			}
+		}
+	}
+	for _, container := range selectableContainers(pod, opts) {
+		if containerPattern.MatchString(container.Name) {
+			matched = append(matched, container)
+		}
+	}
+	return matched
+}
+
+// Function to get the most recently created pod
+func latestPod(pods []v1.Pod) v1.Pod {
+	latest := pods[0]
+	for _, pod := range pods[1:] {
+		if pod.CreationTimestamp.After(latest.CreationTimestamp.Time) {
+			latest = pod
+		}
+	}
+	return latest
+}
+
+// Function to get the pods of a command: every matched pod with -a, else the chosen one
+func selectedPods(ctx context.Context, clientset *kubernetes.Clientset, pattern string, opts Options) ([]v1.Pod, []string) {
+	pods := findPods(ctx, clientset, pattern, opts)
+
+	if !opts.AllPods {
+		pod := choosePod(ctx, clientset, pods, pattern, opts)
+		return []v1.Pod{*pod}, []string{chooseContainer(pod, opts)}
+	}
+
+	containers := make([]string, len(pods))
+	for i, pod := range pods {
+		containers[i] = podContainer(pod, opts)
+	}
+	return pods, containers
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/pterm/pterm"
+)
+
+// How long to wait for the exit status once the log stream ended
+const shutdownStatusWait = 10 * time.Second
+
+// Shutdown sequence of a container, recorded from the moment its pod is Terminating
+type shutdownCapture struct {
+	mu         sync.Mutex
+	started    time.Time
+	grace      int64
+	lines      []string
+	terminated *v1.ContainerStateTerminated
+	done       chan struct{}
+	doneOnce   sync.Once
+}
+
+// Shutdown captures of the followed sources
+var shutdowns sync.Map
+
+// Function to watch the pod of a source and start recording when it enters Terminating
+func watchShutdown(ctx context.Context, clientset *kubernetes.Clientset, src logSource) *shutdownCapture {
+	shutdown := &shutdownCapture{done: make(chan struct{})}
+	shutdowns.Store(src, shutdown)
+
+	go func() {
+		selector := fields.OneTermEqualSelector("metadata.name", src.Pod).String()
+		for ctx.Err() == nil {
+			watcher, err := clientset.CoreV1().Pods(src.Namespace).Watch(ctx, metav1.ListOptions{FieldSelector: selector})
+			if err != nil {
+				time.Sleep(5 * time.Second)
+				continue
+			}
+			for event := range watcher.ResultChan() {
+				if event.Type == watch.Deleted {
+					watcher.Stop()
+					shutdown.finish()
+					return
+				}
+				if pod, ok := event.Object.(*v1.Pod); ok && shutdown.update(src, pod) {
+					watcher.Stop()
+					shutdown.finish()
+					return
+				}
+			}
+			watcher.Stop()
+		}
+	}()
+	return shutdown
+}
+
+// Function to follow the pod state, returning true once the container exited during the shutdown
+func (s *shutdownCapture) update(src logSource, pod *v1.Pod) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if pod.DeletionTimestamp != nil && s.started.IsZero() {
+		s.started = time.Now()
+		if pod.DeletionGracePeriodSeconds != nil {
+			s.grace = *pod.DeletionGracePeriodSeconds
+		}
+		outputMu.Lock()
+		fmt.Fprintln(markerOutput, pterm.FgLightRed.Sprintf("──── pod %s terminating, grace period %ds, capturing shutdown of container %s ────", src.Pod, s.grace, src.Container))
+		outputMu.Unlock()
+	}
+
+	if status := containerStatus(pod, src.Container); status != nil && status.State.Terminated != nil {
+		s.terminated = status.State.Terminated
+		return !s.started.IsZero()
+	}
+	return false
+}
+
+func (s *shutdownCapture) finish() {
+	s.doneOnce.Do(func() { close(s.done) })
+}
+
+// Function to record a line when the shutdown of its container is in progress
+func (s *shutdownCapture) add(line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.started.IsZero() {
+		s.lines = append(s.lines, line)
+	}
+}
+
+// Function to mark the end of the shutdown sequence and save it, returning false if the pod never terminated
+func (s *shutdownCapture) report(src logSource) bool {
+	s.mu.Lock()
+	started := !s.started.IsZero()
+	s.mu.Unlock()
+	if !started {
+		return false
+	}
+
+	// The exit status is published a little after the stream closes
+	select {
+	case <-s.done:
+	case <-time.After(shutdownStatusWait):
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reason := "exit status unknown, pod deleted"
+	if s.terminated != nil {
+		reason = fmt.Sprintf("%s, exit code %d", s.terminated.Reason, s.terminated.ExitCode)
+	}
+	elapsed := time.Since(s.started).Round(time.Second)
+
+	outputMu.Lock()
+	fmt.Fprintln(markerOutput, pterm.FgLightRed.Sprintf("──── container %s of pod %s stopped after %s (%s), %d lines captured ────", src.Container, src.Pod, elapsed, reason, len(s.lines)))
+	outputMu.Unlock()
+
+	path := fmt.Sprintf("shutdown-%s_%s-%s-%s.log", src.Namespace, src.Pod, src.Container, s.started.Format("20060102-150405"))
+	content := fmt.Sprintf("# pod %s/%s container %s\n# terminating at %s, grace period %ds\n%s\n# stopped after %s: %s\n",
+		src.Namespace, src.Pod, src.Container, s.started.Format(time.RFC3339), s.grace, strings.Join(s.lines, "\n"), elapsed, reason)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		pterm.Error.Printf("Error saving shutdown capture: %v\n", err)
+		return true
+	}
+	pterm.Success.Printf("Shutdown sequence saved to %s\n", path)
+	return true
+}
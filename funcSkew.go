@@ -0,0 +1,75 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// Offset between a pod clock and the local one from which timestamps count as skewed
+const skewThreshold = 2 * time.Second
+
+// Lines received this long after the first one of a stream are live, older ones are the backlog
+const skewWarmup = 5 * time.Second
+
+// Estimate per stream how far the timestamps of its node clock are from the local clock
+type skewTracker struct {
+	mu      sync.Mutex
+	first   map[logSource]time.Time
+	offsets map[logSource]time.Duration
+	warned  map[logSource]bool
+}
+
+var clockSkew = &skewTracker{
+	first:   make(map[logSource]time.Time),
+	offsets: make(map[logSource]time.Duration),
+	warned:  make(map[logSource]bool),
+}
+
+// Function to update the clock offset of a stream with a line and return its timestamp, compensated if asked
+//
+// A line is received after it was logged, so the largest timestamp minus receive time seen is
+// the closest estimate of the offset. Timestamps in the future are skew right away, timestamps in
+// the past only once the backlog is read, as old lines are expected then.
+func (s *skewTracker) observe(src logSource, logged, received time.Time, opts Options) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	first, ok := s.first[src]
+	if !ok {
+		first = received
+		s.first[src] = received
+	}
+
+	offset := logged.Sub(received)
+	if offset > 0 || received.Sub(first) >= skewWarmup {
+		if current, ok := s.offsets[src]; !ok || offset > current {
+			s.offsets[src] = offset
+		}
+	}
+
+	estimate, ok := s.offsets[src]
+	if !ok || (estimate < skewThreshold && estimate > -skewThreshold) {
+		return logged
+	}
+
+	if !s.warned[src] {
+		s.warned[src] = true
+		direction := "ahead of"
+		if estimate < 0 {
+			direction = "behind"
+		}
+		advice := ", use --correct-skew to compensate"
+		if opts.CorrectSkew {
+			advice = ", its timestamps are compensated"
+		}
+		pterm.Warning.Printf("Clock of pod '%s' (node '%s') is about %s %s the local clock%s\n",
+			src.Pod, src.Node, estimate.Abs().Round(100*time.Millisecond), direction, advice)
+	}
+
+	if opts.CorrectSkew {
+		return logged.Add(-estimate)
+	}
+	return logged
+}
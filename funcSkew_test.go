@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSkewCorrection(t *testing.T) {
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	src := logSource{Namespace: "shop", Pod: "orders-1", Container: "api", Node: "node-1"}
+
+	// Lines of a stream, in order, with the time they were logged and received
+	type line struct {
+		logged   time.Duration
+		received time.Duration
+		want     time.Duration
+	}
+	tests := []struct {
+		name        string
+		correctSkew bool
+		lines       []line
+	}{
+		{"in sync", true, []line{{0, 100 * time.Millisecond, 0}, {time.Second, 1100 * time.Millisecond, time.Second}}},
+		{"ahead reported only", false, []line{{10 * time.Second, 0, 10 * time.Second}}},
+		{"ahead corrected", true, []line{{10 * time.Second, 0, 0}, {11 * time.Second, time.Second, time.Second}}},
+		{"backlog kept during warmup", true, []line{{-time.Hour, 0, -time.Hour}, {-time.Minute, time.Second, -time.Minute}}},
+		{"behind corrected after warmup", true, []line{{0, 0, 0}, {-4 * time.Second, 6 * time.Second, 6 * time.Second}}},
+		{"below threshold", true, []line{{time.Second, 0, time.Second}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			skew := &skewTracker{
+				first:   make(map[logSource]time.Time),
+				offsets: make(map[logSource]time.Duration),
+				warned:  make(map[logSource]bool),
+			}
+			for i, l := range tt.lines {
+				got := skew.observe(src, start.Add(l.logged), start.Add(l.received), Options{CorrectSkew: tt.correctSkew})
+				if want := start.Add(l.want); !got.Equal(want) {
+					t.Errorf("line %d: got %s, want %s", i, got.Sub(start), l.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// Function to format the last matching line and --snippet-context lines of its stream around it
+// as a Markdown code block to paste in an issue or a chat, and copy it to the clipboard
+func copySnippet(pattern string, opts Options) {
+	selected, ok := selectLine(pattern, opts)
+	if !ok {
+		return
+	}
+
+	// Context lines come from the stream of the selected line only
+	var stream []printedLine
+	at := -1
+	for _, line := range history.snapshot() {
+		if line.src != selected.src {
+			continue
+		}
+		if line.raw == selected.raw && line.time.Equal(selected.time) {
+			at = len(stream)
+		}
+		stream = append(stream, line)
+	}
+	if at < 0 {
+		return
+	}
+	context := max(opts.SnippetContext, 0)
+	from, to := max(at-context, 0), min(at+context+1, len(stream))
+	lines := stream[from:to]
+
+	text := snippetText(lines, currentContext(opts))
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	// OSC 52 asks the terminal to set the system clipboard
+	fmt.Printf("\033]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	pterm.Success.Printf("Copied a snippet of %d lines to clipboard:\n%s", len(lines), text)
+}
+
+// Function to write lines of a stream as a fenced code block under a header of their origin
+func snippetText(lines []printedLine, cluster string) string {
+	src := lines[0].src
+	var b strings.Builder
+	if cluster != "" {
+		fmt.Fprintf(&b, "**Cluster** `%s` · ", cluster)
+	}
+	fmt.Fprintf(&b, "**Pod** `%s/%s` · **Container** `%s` · %s → %s\n", src.Namespace, src.Pod, src.Container,
+		lines[0].time.Format(time.RFC3339), lines[len(lines)-1].time.Format(time.RFC3339))
+	b.WriteString("```\n")
+	for _, line := range lines {
+		fmt.Fprintf(&b, "%s %s\n", line.time.Format(time.RFC3339Nano), line.line)
+	}
+	b.WriteString("```\n")
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// How long lines are held back to be merged with the lines of the other streams
+const sortWindow = 2 * time.Second
+
+// Lines of every stream held back and printed in timestamp order
+type sortBuffer struct {
+	mu    sync.Mutex
+	lines sortedLines
+	opts  Options
+}
+
+type sortedLine struct {
+	printed  printedLine
+	received time.Time
+}
+
+// Heap of the held back lines, the oldest timestamp first
+type sortedLines []sortedLine
+
+func (s sortedLines) Len() int           { return len(s) }
+func (s sortedLines) Less(i, j int) bool { return s[i].printed.time.Before(s[j].printed.time) }
+func (s sortedLines) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s *sortedLines) Push(x any)        { *s = append(*s, x.(sortedLine)) }
+func (s *sortedLines) Pop() any {
+	old := *s
+	line := old[len(old)-1]
+	*s = old[:len(old)-1]
+	return line
+}
+
+var sorter *sortBuffer
+
+// Function to merge the streams by timestamp with --sort instead of printing lines as they arrive
+func startSorting(opts Options) {
+	if !opts.Sort || !opts.AllPods && !opts.AllContainers {
+		return
+	}
+
+	sorter = &sortBuffer{opts: opts}
+	onSessionEnd(sorter.drain)
+	go func() {
+		for range time.Tick(sortWindow / 20) {
+			sorter.flush(time.Now().Add(-sortWindow))
+		}
+	}()
+}
+
+func (s *sortBuffer) add(line printedLine) {
+	s.mu.Lock()
+	heap.Push(&s.lines, sortedLine{printed: line, received: time.Now()})
+	s.mu.Unlock()
+}
+
+// Function to print, in timestamp order, the lines until the oldest one received after the limit
+func (s *sortBuffer) flush(limit time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keyword := currentKeyword()
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	for s.lines.Len() > 0 && !s.lines[0].received.After(limit) {
+		line := heap.Pop(&s.lines).(sortedLine)
+		displayLine(line.printed, keyword)
+	}
+}
+
+// Function to print every line still held back, once every stream ended or the session is closed
+func (s *sortBuffer) drain() {
+	s.flush(time.Now())
+}
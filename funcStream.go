@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// Identify the origin of a log line
+type logSource struct {
+	Namespace string
+	Pod       string
+	Container string
+	Workload  string
+	Node      string
+}
+
+// Parsed log line as emitted on the /stream endpoint
+type logRecord struct {
+	Time      string                 `json:"time"`
+	Namespace string                 `json:"namespace"`
+	Pod       string                 `json:"pod"`
+	Container string                 `json:"container"`
+	Level     string                 `json:"level"`
+	Message   string                 `json:"message"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+	// Length of the message hidden with --metadata-only
+	Length int `json:"length,omitempty"`
+}
+
+// Function to get the record of a displayed line, without its content with --metadata-only
+func (p printedLine) record() logRecord {
+	record := logRecord{
+		Time:      p.time.Format(time.RFC3339Nano),
+		Namespace: p.src.Namespace,
+		Pod:       p.src.Pod,
+		Container: p.src.Container,
+		Level:     p.level,
+		Message:   p.line,
+		Fields:    p.fields,
+	}
+	if p.opts.MetadataOnly {
+		record.Message, record.Fields, record.Length = "", nil, len(p.line)
+	}
+	return record
+}
+
+// Fan out log records to every connected /stream client
+type recordHub struct {
+	mu          sync.Mutex
+	subscribers map[chan logRecord]struct{}
+}
+
+var streamHub *recordHub
+
+func newRecordHub() *recordHub {
+	return &recordHub{subscribers: make(map[chan logRecord]struct{})}
+}
+
+func (h *recordHub) subscribe() chan logRecord {
+	ch := make(chan logRecord, 256)
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *recordHub) unsubscribe(ch chan logRecord) {
+	h.mu.Lock()
+	delete(h.subscribers, ch)
+	h.mu.Unlock()
+}
+
+func (h *recordHub) publish(record logRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers {
+		// Drop the record for slow clients rather than blocking the log stream
+		select {
+		case ch <- record:
+		default:
+		}
+	}
+}
+
+// Serve /stream as NDJSON, or as Server-Sent Events when requested by the client
+func (h *recordHub) serveStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	sse := strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+	if sse {
+		w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher.Flush()
+
+	ch := h.subscribe()
+	defer h.unsubscribe(ch)
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case record := <-ch:
+			data, err := json.Marshal(record)
+			if err != nil {
+				continue
+			}
+			if sse {
+				_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+			} else {
+				_, err = fmt.Fprintf(w, "%s\n", data)
+			}
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func startStreamServer(addr string) {
+	streamHub = newRecordHub()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stream", streamHub.serveStream)
+
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			pterm.Error.Printf("Error serving stream on %s: %v\n", addr, err)
+		}
+	}()
+	pterm.Info.Printf("Streaming parsed logs on http://%s/stream\n", addr)
+}
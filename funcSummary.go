@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pterm/pterm"
+)
+
+// Streams listed by the summary before the rest is counted
+const summaryMaxStreams = 10
+
+// Function to print what the session resolved and let the user confirm it unless --yes is set
+func printSummary(sources []logSource, opts Options) {
+	pterm.Info.Println("Session summary")
+
+	namespaces := "all"
+	if len(opts.Namespaces) > 0 {
+		namespaces = strings.Join(opts.Namespaces, ", ")
+	}
+	fmt.Printf("  context:     %s\n", currentContext(opts))
+	fmt.Printf("  namespaces:  %s\n", namespaces)
+
+	fmt.Printf("  streams:     %d\n", len(sources))
+	for i, src := range sources {
+		if i == summaryMaxStreams {
+			fmt.Printf("               and %d more\n", len(sources)-summaryMaxStreams)
+			break
+		}
+		fmt.Printf("               %s/%s %s (node %s)\n", src.Namespace, src.Pod, src.Container, src.Node)
+	}
+
+	window := "whole log"
+	switch {
+	case opts.SinceTimestamp != "" && opts.TailLines > 0:
+		window = fmt.Sprintf("since %s, last %d lines", opts.SinceTimestamp, opts.TailLines)
+	case opts.SinceTimestamp != "":
+		window = "since " + opts.SinceTimestamp
+	case opts.Since > 0 && opts.TailLines > 0:
+		window = fmt.Sprintf("last %s, last %d lines", opts.Since, opts.TailLines)
+	case opts.Since > 0:
+		window = fmt.Sprintf("last %s", opts.Since)
+	case opts.TailLines > 0:
+		window = fmt.Sprintf("last %d lines", opts.TailLines)
+	}
+	if opts.LastContainer {
+		window += ", previous container"
+	}
+	if opts.LimitBytes > 0 {
+		window += fmt.Sprintf(", first %d bytes", opts.LimitBytes)
+	}
+	fmt.Printf("  window:      %s\n", window)
+
+	var filters []string
+	for _, filter := range []struct{ name, value string }{
+		{"selector", opts.Selector},
+		{"field-selector", opts.FieldSelector},
+		{"only-pods", opts.OnlyPods},
+		{"only-containers", opts.OnlyContainers},
+		{"only-nodes", opts.OnlyNodes},
+		{"exclude-pod", strings.Join(opts.ExcludePods, " ")},
+		{"exclude-container", strings.Join(opts.ExcludeContainers, " ")},
+	} {
+		if filter.value != "" {
+			filters = append(filters, fmt.Sprintf("%s '%s'", filter.name, filter.value))
+		}
+	}
+	if len(activeRules.mute) > 0 {
+		filters = append(filters, fmt.Sprintf("%d mute rules", len(activeRules.mute)))
+	}
+	if len(excludePatterns) > 0 {
+		filters = append(filters, fmt.Sprintf("%d excluded patterns", len(excludePatterns)))
+	}
+	if opts.CollapseProbes {
+		filters = append(filters, "probes collapsed")
+	}
+	if level := minimumLevelName(); level != "" {
+		filters = append(filters, level+" lines and above")
+	}
+	if len(filters) == 0 {
+		filters = append(filters, "none")
+	}
+	fmt.Printf("  filters:     %s\n", strings.Join(filters, ", "))
+
+	var sinks []string
+	if opts.Serve != "" {
+		sinks = append(sinks, "stream on "+opts.Serve)
+	}
+	if opts.Trigger != "" {
+		sinks = append(sinks, fmt.Sprintf("captures on '%s'", opts.Trigger))
+	}
+	if opts.CaptureTermination {
+		sinks = append(sinks, "shutdown captures")
+	}
+	if len(sinks) == 0 {
+		sinks = append(sinks, "terminal only")
+	}
+	fmt.Printf("  sinks:       %s\n", strings.Join(sinks, ", "))
+
+	if opts.Yes || opts.NonInteractive {
+		return
+	}
+	confirmed, _ := pterm.DefaultInteractiveConfirm.WithDefaultText("Start streaming?").Show()
+	if !confirmed {
+		os.Exit(0)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// Fields of the prefix and dashboard templates like {pod}, or {label:<key>} and {annotation:<key>}
+var templateField = regexp.MustCompile(`\{([^{}:]*)(?::([^{}]*))?\}`)
+
+// Fields of a stream in the templates, besides {label:<key>} and {annotation:<key>}
+var sourceFields = map[string]bool{"namespace": true, "pod": true, "container": true, "node": true, "workload": true}
+
+// --prefix-template expanded for each stream, the labels of a pod not changing during the session
+var prefixExpansions sync.Map
+
+// Labels and annotations of the streamed pods, kept from the pod lists for the templates
+var podMetadata sync.Map
+
+type podMeta struct {
+	labels      map[string]string
+	annotations map[string]string
+}
+
+// Function to keep the labels and annotations of a streamed pod
+func rememberPodMetadata(pod v1.Pod) {
+	podMetadata.Store(pod.Namespace+"/"+pod.Name, podMeta{labels: pod.Labels, annotations: pod.Annotations})
+}
+
+// Function to check that a template only uses the fields of a stream
+func validateSourceTemplate(template string) error {
+	for _, match := range templateField.FindAllStringSubmatch(template, -1) {
+		switch {
+		case (match[1] == "label" || match[1] == "annotation") && match[2] != "":
+		case sourceFields[match[1]] && !strings.Contains(match[0], ":"):
+		default:
+			return fmt.Errorf("unknown field %s, use {namespace}, {pod}, {container}, {node}, {workload}, {label:<key>} or {annotation:<key>}", match[0])
+		}
+	}
+	return nil
+}
+
+// Function to get the --prefix-template of a stream, expanded once
+func expandPrefixTemplate(template string, src logSource) string {
+	if cached, ok := prefixExpansions.Load(src); ok {
+		return cached.(string)
+	}
+	prefix := expandSourceTemplate(template, src, func(s string) string { return s }, nil)
+	prefixExpansions.Store(src, prefix)
+	return prefix
+}
+
+// Function to replace in a single pass the fields of a template with the values of a stream, passed
+// through escape: {namespace}, {pod}, {container}, {node}, {workload}, {label:<key>} and
+// {annotation:<key>}, and with the extra values of other fields. Unknown fields are kept as is.
+func expandSourceTemplate(template string, src logSource, escape func(string) string, extra map[string]string) string {
+	var meta podMeta
+	if stored, ok := podMetadata.Load(src.Namespace + "/" + src.Pod); ok {
+		meta = stored.(podMeta)
+	}
+	return templateField.ReplaceAllStringFunc(template, func(field string) string {
+		match := templateField.FindStringSubmatch(field)
+		switch {
+		case match[1] == "label" && match[2] != "":
+			return escape(meta.labels[match[2]])
+		case match[1] == "annotation" && match[2] != "":
+			return escape(meta.annotations[match[2]])
+		case strings.Contains(field, ":"):
+			return field
+		}
+		switch match[1] {
+		case "namespace":
+			return escape(src.Namespace)
+		case "pod":
+			return escape(src.Pod)
+		case "container":
+			return escape(src.Container)
+		case "node":
+			return escape(src.Node)
+		case "workload":
+			return escape(src.Workload)
+		}
+		if value, ok := extra[match[1]]; ok {
+			return value
+		}
+		return field
+	})
+}
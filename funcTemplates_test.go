@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestExpandSourceTemplate(t *testing.T) {
+	src := logSource{Namespace: "shop", Pod: "orders-7d4b9c-x2k8p", Container: "api", Workload: "orders", Node: "node-1"}
+	podMetadata.Store("shop/orders-7d4b9c-x2k8p", podMeta{
+		labels:      map[string]string{"app": "orders", "team": "checkout & payments"},
+		annotations: map[string]string{"owner": "{time}"},
+	})
+	defer podMetadata.Delete("shop/orders-7d4b9c-x2k8p")
+	same := func(s string) string { return s }
+	extra := map[string]string{"time": "2024-03-01T10:00:00Z"}
+
+	tests := []struct {
+		template string
+		escape   func(string) string
+		extra    map[string]string
+		want     string
+	}{
+		{"{namespace}/{pod}", same, nil, "shop/orders-7d4b9c-x2k8p"},
+		{"{workload}:{container}@{node}", same, nil, "orders:api@node-1"},
+		{"{label:app}", same, nil, "orders"},
+		{"{label:missing}", same, nil, ""},
+		{"team={label:team}", url.QueryEscape, nil, "team=checkout+%26+payments"},
+		{"{annotation:owner} {time}", same, extra, "{time} 2024-03-01T10:00:00Z"},
+		{"{time}", same, nil, "{time}"},
+		{"{unknown} {pod:x} {label}", same, nil, "{unknown} {pod:x} {label}"},
+		{`{"datasource":"loki"} {pod}`, same, nil, `{"datasource":"loki"} orders-7d4b9c-x2k8p`},
+	}
+
+	for _, tt := range tests {
+		if got := expandSourceTemplate(tt.template, src, tt.escape, tt.extra); got != tt.want {
+			t.Errorf("expandSourceTemplate(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestValidateSourceTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		valid    bool
+	}{
+		{"", true},
+		{"{namespace}/{pod}/{container}", true},
+		{"{node} {workload} {label:app} {annotation:team}", true},
+		{"plain text", true},
+		{"{podname}", false},
+		{"{time}", false},
+		{"{label}", false},
+		{"{pod:x}", false},
+		{"{}", false},
+	}
+
+	for _, tt := range tests {
+		if err := validateSourceTemplate(tt.template); (err == nil) != tt.valid {
+			t.Errorf("validateSourceTemplate(%q) = %v, want valid %v", tt.template, err, tt.valid)
+		}
+	}
+}
+
+func TestPrefixTemplateGolden(t *testing.T) {
+	podMetadata.Store(fixtureSource.Namespace+"/"+fixtureSource.Pod, podMeta{labels: map[string]string{"app": "orders"}})
+	defer podMetadata.Delete(fixtureSource.Namespace + "/" + fixtureSource.Pod)
+
+	checkFlagGolden(t, "prefix-template", Options{PrefixTemplate: "{namespace}/{label:app}/{pod}"}, ruleFile{})
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/pterm/pterm"
+)
+
+// Function to explain why a followed stream ended
+func printStreamEnd(ctx context.Context, clientset *kubernetes.Clientset, src logSource) {
+	pod, err := clientset.CoreV1().Pods(src.Namespace).Get(ctx, src.Pod, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		pterm.Warning.Printf("Pod '%s' was deleted, stream of container '%s' ended\n", src.Pod, src.Container)
+		return
+	case err != nil:
+		pterm.Warning.Printf("Stream of container '%s' in pod '%s' ended: %v\n", src.Container, src.Pod, err)
+		return
+	}
+
+	reason := terminationReason(pod, src.Container)
+	if pod.DeletionTimestamp != nil {
+		pterm.Warning.Printf("Pod '%s' terminated (%s), stream of container '%s' ended\n", src.Pod, reason, src.Container)
+	} else {
+		pterm.Warning.Printf("Container '%s' in pod '%s' stopped (%s), stream ended\n", src.Container, src.Pod, reason)
+	}
+}
+
+// Function to describe the last termination of a container, e.g. "Error, exit code 1"
+func terminationReason(pod *v1.Pod, container string) string {
+	statuses := append(append([]v1.ContainerStatus{}, pod.Status.ContainerStatuses...), pod.Status.InitContainerStatuses...)
+	for _, status := range statuses {
+		if status.Name != container {
+			continue
+		}
+		terminated := status.State.Terminated
+		if terminated == nil {
+			terminated = status.LastTerminationState.Terminated
+		}
+		if terminated != nil {
+			return fmt.Sprintf("%s, exit code %d", terminated.Reason, terminated.ExitCode)
+		}
+	}
+	return "phase " + string(pod.Status.Phase)
+}
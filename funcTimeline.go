@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/pterm/pterm"
+)
+
+// Number of cells of a timeline strip, each covering --timeline divided by this
+const timelineWidth = 60
+
+// Interval of the redraws of the timeline, and of the blocks printed when stdout isn't a terminal
+const (
+	timelineRedraw   = time.Second
+	timelinePrintout = time.Minute
+)
+
+// Heights of the cells, from no line to the busiest cell of the window
+var timelineLevels = []rune(" ▁▂▃▄▅▆▇█")
+
+// Lines and errors of each pod per time slot, over the last --timeline
+type timelineTracker struct {
+	mu      sync.Mutex
+	slot    time.Duration
+	pods    map[logSource]*[timelineWidth]timelineCell
+	opts    Options
+	live    bool
+	lines   int
+	height  int
+	stopped bool
+}
+
+type timelineCell struct {
+	slot   int64
+	lines  int
+	errors int
+}
+
+var timeline *timelineTracker
+
+// Function to draw with --timeline a strip per pod of its activity and errors, kept in the bottom
+// rows of the terminal or printed as a block every minute when stdout isn't one
+func startTimeline(opts Options) {
+	if opts.Timeline <= 0 {
+		return
+	}
+	slot := opts.Timeline / timelineWidth
+	if slot <= 0 {
+		pterm.Error.Printf("--timeline must be at least %d nanoseconds\n", timelineWidth)
+		os.Exit(1)
+	}
+
+	timeline = &timelineTracker{slot: slot, pods: make(map[logSource]*[timelineWidth]timelineCell), opts: opts}
+	timeline.live = term.IsTerminal(int(os.Stdout.Fd())) && !jsonLines && !opts.Deterministic
+	for _, src := range sessionSources {
+		timeline.pods[logSource{Namespace: src.Namespace, Pod: src.Pod, Workload: src.Workload}] = &[timelineWidth]timelineCell{}
+	}
+	// The scroll region must not outlive klog, however the session ends
+	onSessionEnd(func() {
+		outputMu.Lock()
+		closeTimeline()
+		outputMu.Unlock()
+	})
+
+	if !timeline.live {
+		go func() {
+			for range time.Tick(timelinePrintout) {
+				timeline.printout()
+			}
+		}()
+		return
+	}
+
+	go func() {
+		for range time.Tick(timelineRedraw) {
+			timeline.draw()
+		}
+	}()
+}
+
+// Function to count a line of a stream in the current slot of its pod
+func (t *timelineTracker) observe(src logSource, level string) {
+	key := logSource{Namespace: src.Namespace, Pod: src.Pod, Workload: src.Workload}
+	slot := time.Now().UnixNano() / int64(t.slot)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	cells := t.pods[key]
+	if cells == nil {
+		cells = &[timelineWidth]timelineCell{}
+		t.pods[key] = cells
+	}
+	cell := &cells[slot%timelineWidth]
+	if cell.slot != slot {
+		*cell = timelineCell{slot: slot}
+	}
+	cell.lines++
+	if level == "error" || level == "panic" {
+		cell.errors++
+	}
+}
+
+// Function to render the strips, the height of a cell showing the lines and its color the share of errors
+func (t *timelineTracker) render() []string {
+	now := time.Now().UnixNano() / int64(t.slot)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	keys := make([]logSource, 0, len(t.pods))
+	busiest, labelWidth := 1, 0
+	for key, cells := range t.pods {
+		keys = append(keys, key)
+		labelWidth = max(labelWidth, len(key.Pod))
+		for _, cell := range cells {
+			if now-cell.slot < timelineWidth {
+				busiest = max(busiest, cell.lines)
+			}
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Namespace+"/"+keys[i].Pod < keys[j].Namespace+"/"+keys[j].Pod
+	})
+
+	lines := []string{pterm.FgDarkGray.Sprintf("%-*s  -%s%snow", labelWidth, "", t.opts.Timeline, strings.Repeat(" ", max(timelineWidth-len(t.opts.Timeline.String())-4, 0)))}
+	for _, key := range keys {
+		var strip strings.Builder
+		for slot := now - timelineWidth + 1; slot <= now; slot++ {
+			cell := t.pods[key][slot%timelineWidth]
+			if cell.slot != slot || cell.lines == 0 {
+				strip.WriteString(pterm.FgDarkGray.Sprint("·"))
+				continue
+			}
+			height := timelineLevels[1+(cell.lines*(len(timelineLevels)-1)-1)/busiest]
+			switch {
+			case cell.errors*10 >= cell.lines:
+				strip.WriteString(pterm.Red(string(height)))
+			case cell.errors > 0:
+				strip.WriteString(pterm.Yellow(string(height)))
+			default:
+				strip.WriteString(pterm.Green(string(height)))
+			}
+		}
+		lines = append(lines, podColor(key, t.opts).Sprintf("%-*s", labelWidth, key.Pod)+"  "+strip.String())
+	}
+	return lines
+}
+
+// Function to redraw the strips in the rows kept below the scroll region of the stream
+func (t *timelineTracker) draw() {
+	lines := t.render()
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	_, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || t.stopped || len(lines) >= height {
+		return
+	}
+
+	if len(lines) != t.lines || height != t.height {
+		// Scroll the stream up to free the rows, then keep it above them
+		fmt.Print(strings.Repeat("\n", len(lines)))
+		fmt.Printf("\033[%dA\0337\033[1;%dr\0338", len(lines), height-len(lines))
+		t.lines, t.height = len(lines), height
+	}
+	fmt.Print("\0337")
+	for i, line := range lines {
+		fmt.Printf("\033[%d;1H\033[2K%s", height-len(lines)+1+i, line)
+	}
+	fmt.Print("\0338")
+}
+
+// Function to print the strips as a block of the stream when they can't stay on screen
+func (t *timelineTracker) printout() {
+	lines := t.render()
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	fmt.Fprintln(markerOutput, strings.Join(lines, "\n"))
+}
+
+// Function to give the rows of the timeline back to the terminal once the streams ended, with outputMu held
+func closeTimeline() {
+	if timeline == nil || !timeline.live || timeline.lines == 0 {
+		return
+	}
+	timeline.stopped = true
+	fmt.Printf("\033[r\033[%d;1H", timeline.height)
+	for _, line := range timeline.render() {
+		fmt.Println(line)
+	}
+}
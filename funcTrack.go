@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// Interval of the checks of the rolling statistics of --track-field
+const trackInterval = time.Second
+
+// Values of --track-field received during the last --track-window, alerting when their
+// average or p95 crosses --warn-over
+type fieldTracker struct {
+	mu        sync.Mutex
+	field     string
+	threshold float64
+	window    time.Duration
+	samples   []fieldSample
+	over      bool
+}
+
+type fieldSample struct {
+	time  time.Time
+	value float64
+}
+
+var tracked *fieldTracker
+
+// Function to start tracking the numeric field of --track-field
+func startFieldTracking(opts Options) {
+	if opts.TrackField == "" {
+		return
+	}
+	if opts.TrackWindow <= 0 {
+		pterm.Error.Println("--track-window must be positive")
+		os.Exit(1)
+	}
+
+	tracked = &fieldTracker{field: opts.TrackField, threshold: opts.WarnOver, window: opts.TrackWindow}
+	go func() {
+		for range time.Tick(trackInterval) {
+			tracked.check()
+		}
+	}()
+}
+
+// Function to record the value of the field of a line, returning the tag of a value over the threshold
+func (f *fieldTracker) observe(fields map[string]interface{}) string {
+	var value float64
+	switch v := lookupField(fields, f.field).(type) {
+	case float64:
+		value = v
+	case string:
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return ""
+		}
+		value = parsed
+	default:
+		return ""
+	}
+
+	f.mu.Lock()
+	f.samples = append(f.samples, fieldSample{time: time.Now(), value: value})
+	f.mu.Unlock()
+
+	if value > f.threshold {
+		return " " + pterm.FgYellow.Sprintf("%s>%g", f.field, f.threshold)
+	}
+	return ""
+}
+
+// Function to compute the average and p95 of the window and print when they cross the threshold
+func (f *fieldTracker) check() {
+	f.mu.Lock()
+	limit := time.Now().Add(-f.window)
+	kept := 0
+	for kept < len(f.samples) && f.samples[kept].time.Before(limit) {
+		kept++
+	}
+	f.samples = f.samples[kept:]
+	values := make([]float64, len(f.samples))
+	for i, sample := range f.samples {
+		values[i] = sample.value
+	}
+	f.mu.Unlock()
+
+	average, p95 := fieldStatistics(values)
+	over := len(values) > 0 && (average > f.threshold || p95 > f.threshold)
+	if over == f.over {
+		return
+	}
+	f.over = over
+
+	stats := fmt.Sprintf("average %.4g, p95 %.4g over the last %s (%d lines)", average, p95, f.window, len(values))
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	if over {
+		fmt.Fprintln(markerOutput, pterm.FgYellow.Sprintf("──── %s over %g: %s ────", f.field, f.threshold, stats))
+	} else {
+		fmt.Fprintln(markerOutput, pterm.FgGreen.Sprintf("──── %s back under %g: %s ────", f.field, f.threshold, stats))
+	}
+}
+
+// Function to get the average and the 95th percentile (nearest rank) of values
+func fieldStatistics(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	sort.Float64s(values)
+	rank := int(math.Ceil(0.95*float64(len(values)))) - 1
+	return sum / float64(len(values)), values[rank]
+}
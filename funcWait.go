@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Function to get the status of a container, init and ephemeral containers included
+func containerStatus(pod *v1.Pod, container string) *v1.ContainerStatus {
+	statuses := append(append([]v1.ContainerStatus{}, pod.Status.ContainerStatuses...), pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.EphemeralContainerStatuses...)
+	for i := range statuses {
+		if statuses[i].Name == container {
+			return &statuses[i]
+		}
+	}
+	return nil
+}
+
+// Function to describe why a container has no logs yet, empty once it can be streamed
+func waitingReason(pod *v1.Pod, container string) string {
+	if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+		return ""
+	}
+
+	status := containerStatus(pod, container)
+	if status == nil {
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == v1.PodScheduled && condition.Status != v1.ConditionTrue && condition.Reason != "" {
+				return condition.Reason + ": " + condition.Message
+			}
+		}
+		return string(pod.Status.Phase)
+	}
+
+	// A restarting container still has the logs of its previous run
+	waiting := status.State.Waiting
+	if waiting == nil || status.RestartCount > 0 {
+		return ""
+	}
+	if waiting.Message != "" {
+		return waiting.Reason + ": " + waiting.Message
+	}
+	return waiting.Reason
+}
+
+func waitingText(pod *v1.Pod, container string, reason string) string {
+	return fmt.Sprintf("Waiting for container '%s' in pod '%s' to start (%s)", container, pod.Name, reason)
+}
+
+// Function to wait until a container started, reporting what blocks it, and return the updated pod
+func waitForContainer(ctx context.Context, clientset *kubernetes.Clientset, pod *v1.Pod, container string, start func(string) progress) (*v1.Pod, error) {
+	reason := waitingReason(pod, container)
+	if reason == "" {
+		return pod, nil
+	}
+
+	spinner := start(waitingText(pod, container, reason))
+	pods := clientset.CoreV1().Pods(pod.Namespace)
+	selector := fields.OneTermEqualSelector("metadata.name", pod.Name).String()
+
+	for {
+		watcher, err := pods.Watch(ctx, metav1.ListOptions{FieldSelector: selector, ResourceVersion: pod.ResourceVersion})
+		if err != nil {
+			spinner.Fail(fmt.Sprintf("Unable to watch pod '%s'", pod.Name))
+			return nil, err
+		}
+
+		for event := range watcher.ResultChan() {
+			if event.Type == watch.Deleted {
+				watcher.Stop()
+				spinner.Fail(fmt.Sprintf("Pod '%s' was deleted", pod.Name))
+				return nil, fmt.Errorf("pod '%s' was deleted before container '%s' started", pod.Name, container)
+			}
+			updated, ok := event.Object.(*v1.Pod)
+			if !ok {
+				// Expired resource version, get the pod again below
+				break
+			}
+
+			pod = updated
+			next := waitingReason(pod, container)
+			if next == "" {
+				watcher.Stop()
+				spinner.Success(fmt.Sprintf("Container '%s' in pod '%s' started", container, pod.Name))
+				return pod, nil
+			}
+			if next != reason {
+				reason = next
+				spinner.UpdateText(waitingText(pod, container, reason))
+			}
+		}
+		watcher.Stop()
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		updated, err := pods.Get(ctx, pod.Name, metav1.GetOptions{})
+		if err != nil {
+			spinner.Fail(fmt.Sprintf("Unable to get pod '%s'", pod.Name))
+			return nil, err
+		}
+		pod = updated
+		if waitingReason(pod, container) == "" {
+			spinner.Success(fmt.Sprintf("Container '%s' in pod '%s' started", container, pod.Name))
+			return pod, nil
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Workload kinds accepted in <kind>/<name> targets, by their kubectl names and short names
+var workloadKinds = map[string]string{
+	"deploy":       "Deployment",
+	"deployment":   "Deployment",
+	"deployments":  "Deployment",
+	"sts":          "StatefulSet",
+	"statefulset":  "StatefulSet",
+	"statefulsets": "StatefulSet",
+	"ds":           "DaemonSet",
+	"daemonset":    "DaemonSet",
+	"daemonsets":   "DaemonSet",
+	"job":          "Job",
+	"jobs":         "Job",
+}
+
+// Function to split a deploy/<name>, sts/<name>, ds/<name> or job/<name> target into its kind and name
+func parseWorkload(pattern string) (kind, name string, ok bool) {
+	prefix, name, found := strings.Cut(pattern, "/")
+	if !found || name == "" {
+		return "", "", false
+	}
+	kind, ok = workloadKinds[strings.ToLower(prefix)]
+	return kind, name, ok
+}
+
+// Function to keep the pods controlled by a workload, through its ReplicaSets for a Deployment
+func workloadPods(ctx context.Context, clientset *kubernetes.Clientset, pods []v1.Pod, namespaces []string, kind, name string) ([]v1.Pod, error) {
+	// Deployments own their pods through ReplicaSets, of the current and of the previous rollouts
+	replicaSets := make(map[string]bool)
+	if kind == "Deployment" {
+		for _, namespace := range namespaces {
+			list, err := clientset.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("listing replicasets: %w", err)
+			}
+			for _, rs := range list.Items {
+				if owner := metav1.GetControllerOf(&rs); owner != nil && owner.Kind == kind && owner.Name == name {
+					replicaSets[rs.Namespace+"/"+rs.Name] = true
+				}
+			}
+		}
+	}
+
+	var matched []v1.Pod
+	for _, pod := range pods {
+		owner := metav1.GetControllerOf(&pod)
+		if owner == nil {
+			continue
+		}
+		if kind == "Deployment" {
+			if owner.Kind == "ReplicaSet" && replicaSets[pod.Namespace+"/"+owner.Name] {
+				matched = append(matched, pod)
+			}
+		} else if owner.Kind == kind && owner.Name == name {
+			matched = append(matched, pod)
+		}
+	}
+	return matched, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/pterm/pterm"
+)
+
+// Lines of the other streams held back while zoomed on one, printed when leaving the zoom
+const zoomBufferSize = 1000
+
+type zoomState struct {
+	mu      sync.Mutex
+	source  *logSource
+	held    []printedLine
+	dropped int
+}
+
+var zoom = &zoomState{}
+
+// Function to hold back a line of another stream than the zoomed one
+func (z *zoomState) hold(line printedLine) bool {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	if z.source == nil || *z.source == line.src {
+		return false
+	}
+	if len(z.held) == zoomBufferSize {
+		z.held = z.held[1:]
+		z.dropped++
+	}
+	z.held = append(z.held, line)
+	return true
+}
+
+// Function to zoom on the n-th stream of the legend, or to leave the zoom
+func toggleZoom(n int, opts Options) {
+	if n < 1 || n > len(sessionSources) {
+		pterm.Warning.Printf("No stream %d, type l to list them\n", n)
+		return
+	}
+	src := sessionSources[n-1]
+
+	zoom.mu.Lock()
+	defer zoom.mu.Unlock()
+	if zoom.source != nil {
+		unzoomed := *zoom.source
+		held, dropped := zoom.held, zoom.dropped
+		zoom.source, zoom.held, zoom.dropped = nil, nil, 0
+
+		keyword := currentKeyword()
+		outputMu.Lock()
+		pterm.Info.Printf("Leaving the zoom on pod '%s', %d lines of the other streams held back:\n", unzoomed.Pod, len(held))
+		if dropped > 0 {
+			pterm.Warning.Printf("%d older lines were dropped\n", dropped)
+		}
+		for _, line := range held {
+			displayLine(line, keyword)
+		}
+		outputMu.Unlock()
+
+		if unzoomed == src {
+			return
+		}
+	}
+
+	zoom.source = &src
+	outputMu.Lock()
+	pterm.Info.Printf("Zoomed on %s %s, type %d again to show every stream\n", sourcePrefix(src, opts), src.Container, n)
+	outputMu.Unlock()
+}
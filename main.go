@@ -1,331 +1,454 @@
 package main
 
 import (
-	"bufio"
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
 	"regexp"
-	"strings"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
 
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
-const (
-	timestampFormat = "2006-01-02T15:04:05.000"
-	errorKeywords   = "level=error|level=err|levelerror|err=|[error]|[ERROR]|[err]|[ERR]| ERRO: | Err: | ERR | ERROR | CRIT "
-	warningKeywords = "level=warning|level=warn|levelwarn|warn=|[warning]|[WARNING]|[warn]|[WARN]| WARN: | WARN | WARNING "
-	panicKeywords   = "level=panic|levelpanic|[panic]|[PANIC]| panic:|PANIC "
-	debugKeywords   = "level=debug|leveldebug|[debug]|[DEBUG]| debug:|DEBUG "
-
-	errorLevelJson = "error|critical|fatal"
-	warnLevelJson  = "warn|warning|panic"
-	debugLevelJson = "debug"
-)
+// Options of a klog invocation, read from the flags once and then only passed by value
+type Options struct {
+	Container         string
+	Keyword           string
+	Timestamp         bool
+	HideTimestamps    bool
+	InitContainers    bool
+	ExcludeContainers []string
+	Force             bool
+	IfNone            string
+	ExcludePods       []string
+	LastContainer     bool
+	SinceTime         int
+	Since             time.Duration
+	CollapseProbes    bool
+	NewErrors         bool
+	Sort              bool
+	SinceTimestamp    string
+	TailLines         int
+	LimitBytes        int64
+	Serve             string
+	NoCache           bool
+
+	PrintKubectl  bool
+	Dump          string
+	WithManifest  bool
+	AllPods       bool
+	CrossPod      bool
+	Trigger       string
+	Capture       int
+	ExitIdle      time.Duration
+	MaxSession    time.Duration
+	Dashboards    []string
+	CorrectSkew   bool
+	AllContainers bool
+	ColorBy       string
+
+	OnlyPods       string
+	OnlyContainers string
+	OnlyNodes      string
+
+	Source  string
+	Query   string
+	LokiURL string
+
+	Rollouts           bool
+	CaptureTermination bool
+	ExportSanitized    string
+	Summary            bool
+	Legend             bool
+	Control            bool
+	OutputFile         string
+	OutputPlain        bool
+	OutputDir          string
+	OutputOnly         bool
+	OutputMaxSize      int
+	OutputMaxFiles     int
+	OutputCompress     bool
+	LineOutput         string
+	CodeURL            string
+	PrettyJSON         bool
+	SnippetContext     int
+	Fields             []string
+	LevelKeys          []string
+	WithPeers          bool
+	EmitClassified     string
+	Exclude            []string
+	Timeline           time.Duration
+	IgnoreCase         bool
+	KeywordRegex       bool
+	PrefixTemplate     string
+	MetadataOnly       bool
+	PairRequests       bool
+	TrackField         string
+	WarnOver           float64
+	TrackWindow        time.Duration
+	Revision           bool
+	Prescreen          bool
+	Level              string
+	WithExec           string
+	ExecInterval       time.Duration
+	ControlPid         int
+
+	Yes         bool
+	FetchBudget int
+
+	Deterministic  bool
+	NonInteractive bool
+	Context        string
+	Kubeconfig     string
+	Selector       string
+	Namespaces     []string
+	AllNamespaces  bool
+	FieldSelector  string
+	Rules          string
+	Config         string
+	RulesSample    string
+	Latest         bool
+
+	WindowA string
+	WindowB string
+
+	Output string
+}
 
-var (
-	containerFlag string
-	keywordFlag   string
-	timestampFlag bool
-	lastContainer bool
-	sinceTimeFlag int
-	tailLinesFlag int
-)
+// Flags of the command line, copied into the Options of the invocation
+var flags Options
 
 var rootCmd = &cobra.Command{
 	Use:   "klog",
 	Short: "Stream Kubernetes pod logs.",
-	Run: func(cmd *cobra.Command, args []string) {
-		if len(args) == 0 {
-			pterm.Error.Println("Pod name required")
-			_ = cmd.Usage()
-			os.Exit(128)
+	Run:   runFollow,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Prompts can't be answered without a terminal
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			flags.NonInteractive = true
+		}
+		warnDeprecatedFlags(os.Args[1:])
+		flags.Context = resolveContext(flags)
+		if flags.SinceTime > 0 && flags.Since > 0 || flags.SinceTimestamp != "" && (flags.SinceTime > 0 || flags.Since > 0) {
+			pterm.Error.Println("Use only one of -s, --since and --since-time")
+			os.Exit(1)
+		}
+		if _, err := time.Parse(time.RFC3339, flags.SinceTimestamp); flags.SinceTimestamp != "" && err != nil {
+			pterm.Error.Printf("Invalid --since-time: %v\n", err)
+			os.Exit(1)
+		}
+		if flags.SinceTime > 0 {
+			flags.Since = time.Duration(flags.SinceTime) * time.Hour
+		}
+		if flags.AllNamespaces && len(flags.Namespaces) > 0 {
+			pterm.Error.Println("Use either -n or -A")
+			os.Exit(1)
 		}
-
-		podFlag := args[0]
-		klog(podFlag, containerFlag, keywordFlag)
 	},
 }
 
-func init() {
-	// Set the help template for rootCmd
-	rootCmd.SetHelpTemplate(rootCmd.HelpTemplate() + `
-Examples:
-  klog <pod-name> -t			// Select containers and show logs for <pod-name> with timestamp
-  klog <pod-name> -c <my-container> -l	// Show logs for <my-container> in <pod-name> for last container
-  klog <pod-name> -k <my-keyword>	// Show logs for <pod-name> and color the <my-keyword> in line
-  klog <pod-name> -s 24 - 50		// Show logs for <pod-name> with sinceTime 24 hours and last 50 tailLines
-`)
-	// Set flags for arguments
-	rootCmd.Flags().StringVarP(&containerFlag, "container", "c", "", "Container name")
-	rootCmd.Flags().StringVarP(&keywordFlag, "keyword", "k", "", "Keyword for highlighting")
-	rootCmd.Flags().BoolVarP(&timestampFlag, "timestamp", "t", false, "Display timestamps in logs")
-	rootCmd.Flags().BoolVarP(&lastContainer, "lastContainer", "l", false, "Display logs for the previous container")
-	rootCmd.Flags().IntVarP(&sinceTimeFlag, "sinceTime", "s", 0, "Show logs since N hours ago")
-	rootCmd.Flags().IntVarP(&tailLinesFlag, "tailLines", "T", 0, "Show last N lines of logs")
+var followCmd = &cobra.Command{
+	Use:     "follow <pod-name>",
+	Short:   "Stream the logs of a pod, the default command.",
+	Example: "  klog follow my-api -c app -t",
+	Run:     runFollow,
 }
 
-func main() {
-	if err := rootCmd.Execute(); err != nil {
-		pterm.Error.Print(err)
-	}
-}
-
-// Function to highlight a word in the string
-func highlightKeyword(line string, keyword string, colorFunc func(a ...interface{}) string) string {
-	re := regexp.MustCompile(keyword)
-	matches := re.FindAllStringIndex(line, -1)
-
-	if len(matches) > 0 {
-		result := ""
-		startIndex := 0
-		for _, match := range matches {
-			result += colorFunc(line[startIndex:match[0]]) + pterm.BgMagenta.Sprint(line[match[0]:match[1]])
-			startIndex = match[1]
+var watchNamespaceCmd = &cobra.Command{
+	Use:     "watch-namespace <namespace>",
+	Short:   "Follow every pod of a namespace, only printing warnings and errors by default.",
+	Example: "  klog watch-namespace team-a --min-level error",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		flags.Namespaces = []string{args[0]}
+		flags.AllPods = true
+		if !cmd.Flags().Changed("min-level") {
+			flags.Level = "warning+"
 		}
-		result += colorFunc(line[startIndex:])
-		return result
-	}
-
-	return colorFunc(line)
-}
-
-func containsAny(line string, substrings ...string) bool {
-	for _, s := range substrings {
-		if strings.Contains((line), s) {
-			return true
+		// Pods of an empty namespace are waited for, the radar stays up
+		if !cmd.Flags().Changed("if-none") {
+			flags.IfNone = "wait"
 		}
-	}
-	return false
+		runFollow(cmd, []string{".*"})
+	},
 }
 
-func printLogLine(line string, keyword string) {
-	var logEntry map[string]interface{}
-	var colorFunc func(a ...interface{}) string
-	var timestamp string
-
-	if timestampFlag {
-		// Extract timestamp and rest of the line
-		if parts := strings.SplitN(line, " ", 2); len(parts) == 2 {
-			timestamp = parts[0]
-			line = parts[1]
-		}
+func runFollow(cmd *cobra.Command, args []string) {
+	opts := flags
+	initLineOutput(opts)
+	initMinimumLevel(opts)
+	if err := validateSourceTemplate(opts.PrefixTemplate); err != nil {
+		pterm.Error.Printf("Invalid --prefix-template: %v\n", err)
+		os.Exit(1)
 	}
-
-	switch {
-	case containsAny(line, strings.Split(errorKeywords, "|")...):
-		colorFunc = pterm.Red
-	case containsAny(line, strings.Split(warningKeywords, "|")...):
-		colorFunc = pterm.Yellow
-	case containsAny(line, strings.Split(panicKeywords, "|")...):
-		colorFunc = pterm.Yellow
-	case containsAny(line, strings.Split(debugKeywords, "|")...):
-		colorFunc = pterm.Cyan
-	default:
-		colorFunc = pterm.White
+	initRules(opts)
+	initKeyword(opts)
+	if err := initMetadataFilter(opts); err != nil {
+		pterm.Error.Printf("Invalid filter: %v\n", err)
+		os.Exit(1)
 	}
-
-	if err := json.Unmarshal([]byte(line), &logEntry); err == nil {
-		level, exists := logEntry["level"].(string)
-		if exists {
-			levelLower := strings.ToLower(level)
-			switch {
-			case containsAny(levelLower, strings.Split(errorLevelJson, "|")...):
-				colorFunc = pterm.Red
-			case containsAny(levelLower, strings.Split(warnLevelJson, "|")...):
-				colorFunc = pterm.Yellow
-			case containsAny(levelLower, strings.Split(debugLevelJson, "|")...):
-				colorFunc = pterm.Cyan
-			default:
-				colorFunc = pterm.White
-			}
-		}
+	if err := initExcludes(opts); err != nil {
+		pterm.Error.Printf("Invalid exclude: %v\n", err)
+		os.Exit(1)
 	}
-
-	// Convert timestamp string to time.Time object
-	if timestamp != "" {
-		t, err := time.Parse(time.RFC3339Nano, timestamp)
-		if err == nil {
-			timestamp = t.Format(timestampFormat)
-		}
+	if opts.Serve != "" {
+		startStreamServer(opts.Serve)
 	}
 
-	if keyword == "" {
-		fmt.Printf("%s %s\n", pterm.FgDarkGray.Sprint(timestamp), colorFunc(line))
-	} else {
-		// Apply colorization to the rest of the line
-		coloredLine := highlightKeyword(colorFunc(line), keyword, colorFunc)
-
-		// Print timestamp normally and the rest colored
-		fmt.Printf("%s %s\n", pterm.FgDarkGray.Sprint(timestamp), coloredLine)
+	if opts.Source == "loki" {
+		streamLoki(opts)
+		return
 	}
-}
 
-func selectContainer(containers []v1.Container) string {
-	// If only one container is available, return its name directly
-	if len(containers) == 1 {
-		return containers[0].Name
+	if len(args) == 0 && opts.Selector == "" && opts.FieldSelector == "" {
+		pterm.Error.Println("Pod name, --selector or --field-selector required")
+		_ = cmd.Usage()
+		os.Exit(128)
 	}
 
-	// Use container names in interactive interface
-	selectorContainer := pterm.DefaultInteractiveSelect.WithDefaultText("Select a container")
-	selectorContainer.MaxHeight = 10
-
-	// Create a slice of strings to store container names
-	containerNames := make([]string, len(containers))
-	for i, container := range containers {
-		containerNames[i] = container.Name
+	var podFlag string
+	if len(args) > 0 {
+		podFlag = args[0]
 	}
-
-	selectedOption, _ := selectorContainer.WithOptions(containerNames).Show()
-
-	fmt.Print("\033[F\033[K\033[F\033[K") // Remove last 2 lines
-	return selectedOption
-}
-
-func selectPod(matchedPods []v1.Pod) string {
-	if len(matchedPods) == 1 {
-		return matchedPods[0].Name
+	if opts.Dump != "" {
+		dump(podFlag, opts)
+		return
 	}
-
-	podNames := make([]string, len(matchedPods))
-	for i, pod := range matchedPods {
-		podNames[i] = pod.Name
+	if opts.ExportSanitized != "" {
+		exportSanitized(podFlag, opts)
+		return
 	}
-
-	selectorPod := pterm.DefaultInteractiveSelect.WithDefaultText("Select a pod")
-	selectorPod.MaxHeight = 10
-	selectedOption, _ := selectorPod.WithOptions(podNames).Show() // The Show() method displays the options and waits for the user's input
-
-	fmt.Print("\033[F\033[K\033[F\033[K") // Remove last 2 lines
-	return selectedOption
+	follow(podFlag, opts)
+	finishSession()
 }
 
-func klog(pod string, container string, keyword string) {
-	// Create spinner & Start
-	spinner, _ := pterm.DefaultSpinner.Start("Initialization in progress")
-
-	var matchedPods []v1.Pod
-	var namespace string
-	var selectedPodName string
-	var podName string
-
-	config := loadKubeConfig()
-	ctx := context.Background()
-
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		pterm.Error.Printf("Error creating Kubernetes client: %v\n", err)
-		os.Exit(1)
-	}
+func init() {
+	// Pod names are arguments of the root command, next to its subcommands
+	rootCmd.Args = cobra.ArbitraryArgs
+	rootCmd.AddCommand(followCmd, dumpCmd, listCmd, checkCmd, analyzeCmd, replayCmd, compareCmd, rulesCmd, ctlCmd, watchNamespaceCmd)
 
-	allPods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
-	if err != nil {
-		pterm.Error.Printf("Error fetching pods: %v\n", err)
-		os.Exit(1)
+	// Subcommands don't share the examples of the root help
+	for _, cmd := range rootCmd.Commands() {
+		cmd.SetHelpTemplate((&cobra.Command{}).HelpTemplate())
 	}
 
-	for _, p := range allPods.Items {
-		if matched, _ := regexp.MatchString(pod, p.Name); matched {
-			matchedPods = append(matchedPods, p)
-		}
-	}
+	// Set the help template for rootCmd
+	rootCmd.SetHelpTemplate(rootCmd.HelpTemplate() + `
+Examples:
+  klog <pod-name> -t			// Select containers and show logs for <pod-name> with timestamp
+  klog <pod-name> -c <my-container> -l	// Show logs for <my-container> in <pod-name> for last container
+  klog <pod-name> -k <my-keyword>	// Show logs for <pod-name> and color the <my-keyword> in line
+  klog <pod-name> -s 24 -T 50		// Show logs for <pod-name> of the last 24 hours, at most the last 50 lines
+  klog --selector app=frontend -a	// Show logs of all pods labeled app=frontend
+  klog deploy/my-api -a			// Show logs of the pods of Deployment my-api (also sts/, ds/ and job/)
+  klog <pod-name> --all-containers	// Show logs of every container of <pod-name>, lines prefixed with pod/container
+  klog <pod-name> -a --cross-pod	// Show logs of all pods matching <pod-name> and flag errors shared by several pods
+  klog <pod-name> -a --trigger 'OutOfMemory|deadlock'	// Save context of all pods matching <pod-name> when a trigger line appears
+  klog <pod-name> --exit-idle 10m	// Stop following <pod-name> after 10 minutes without logs
+  klog --source loki --query '{app="payments"}' -s 24	// Show logs of the last 24 hours from Loki and follow them
+  klog dump <pod-name> ./incident --with-manifest	// Save the logs and YAML of <pod-name> into ./incident
+  klog analyze <pod-name> -a -s 1	// Summarize levels and frequent messages of the last hour of all pods matching <pod-name>
+  klog <pod-name> --print-kubectl	// Print the kubectl logs command matching the selection of <pod-name>
+  klog <pod-name> --serve :8080		// Show logs for <pod-name> and expose them as NDJSON on http://localhost:8080/stream
+`)
+	// Set flags selecting pods and containers, shared by every command
+	rootCmd.SetGlobalNormalizationFunc(normalizeFlagName)
+	selection := rootCmd.PersistentFlags()
+	selection.StringVarP(&flags.Container, "container", "c", "", "Container name, or regex matched against the container names of the pod (e.g. 'app|worker')")
+	selection.BoolVarP(&flags.Timestamp, "timestamp", "t", false, "Display timestamps in logs")
+	selection.BoolVar(&flags.HideTimestamps, "hide-timestamps", false, "Request timestamps to order lines (clock skew, time of inspected lines) without displaying them")
+	selection.BoolVar(&flags.InitContainers, "init-containers", false, "Offer init containers in the container selector, streamed before the main ones with --all-containers")
+	selection.StringArrayVar(&flags.ExcludePods, "exclude-pod", nil, "Regex of pods left out after matching, e.g. 'gateway' (repeatable)")
+	selection.StringArrayVar(&flags.ExcludeContainers, "exclude-container", nil, "Regex of containers never selected nor streamed, e.g. 'istio-proxy|linkerd-proxy' (repeatable)")
+	selection.StringVar(&flags.IfNone, "if-none", "error", "When no pod matches: 'wait' for one, 'error' (exit 1) or 'ok' (exit 0)")
+	selection.BoolVar(&flags.Force, "force", false, "Also stream the pods of the namespaces protected by the config")
+	selection.BoolVarP(&flags.LastContainer, "previous", "l", false, "Display logs for the previous container")
+	selection.IntVarP(&flags.SinceTime, "since-hours", "s", 0, "Show logs since N hours ago")
+	selection.DurationVar(&flags.Since, "since", 0, "Show logs since this duration ago, e.g. 5m, 90s or 2h30m")
+	selection.StringVar(&flags.SinceTimestamp, "since-time", "", "Show logs since this RFC3339 time, e.g. 2024-05-03T14:00:00Z")
+	selection.IntVarP(&flags.TailLines, "tail", "T", 0, "Show last N lines of logs")
+	selection.Int64Var(&flags.LimitBytes, "limit-bytes", 0, "Stop each log stream after N bytes, to sample noisy pods on slow connections")
+	selection.BoolVarP(&flags.AllPods, "all-pods", "a", false, "Stream logs of all matching pods at once")
+	selection.BoolVarP(&flags.Yes, "yes", "y", false, "Don't ask for confirmation")
+	selection.IntVar(&flags.FetchBudget, "fetch-budget", 100, "Ask for confirmation when the logs to fetch are estimated above N MiB")
+	selection.BoolVar(&flags.NoCache, "no-cache", false, "Always list pods from the API server instead of the local cache")
+	selection.StringVar(&flags.Rules, "rules", "", "Rules file with highlight, mute and level rules (default: klog/rules.yaml in the user config directory)")
+	selection.StringArrayVar(&flags.LevelKeys, "level-key", nil, "Key of the level in JSON lines besides 'level', e.g. 'severity' or 'log.level' (repeatable)")
+	selection.StringVar(&flags.Config, "config", os.Getenv("KLOG_CONFIG"), "Shared config applied before the local rules: a file, a URL or configmap://<namespace>/<name>[/<key>], defaults to $KLOG_CONFIG")
+	selection.StringSliceVarP(&flags.Namespaces, "namespace", "n", nil, "Only search pods in these namespaces, comma-separated or repeated (default: all namespaces)")
+	selection.BoolVarP(&flags.AllNamespaces, "all-namespaces", "A", false, "Search pods in all namespaces, tell identically named pods apart and prefix lines with the namespace")
+	selection.StringVar(&flags.Selector, "selector", "", "Only match pods with these labels, e.g. 'app=frontend,tier!=cache', the pod name becomes optional")
+	selection.StringVar(&flags.FieldSelector, "field-selector", "", "Filter pods on the API server by fields, e.g. 'spec.nodeName=node-3,status.phase=Running'")
+	selection.StringVar(&flags.Kubeconfig, "kubeconfig", "", "Path to the kubeconfig file, defaults to $KUBECONFIG then ~/.kube/config")
+	selection.StringVar(&flags.Context, "context", "", "Kubeconfig context to use instead of the current one, picked among the matching ones when ambiguous")
+	selection.BoolVar(&flags.Latest, "latest", false, "Select the most recently created of the matching pods instead of asking")
+	selection.BoolVar(&flags.NonInteractive, "non-interactive", false, "Never prompt, fail when a choice is needed (default when stdin is not a terminal)")
+	selection.BoolVar(&flags.Deterministic, "deterministic", false, "Reproducible output for tests and recordings: colors in pod name order, UTC timestamps, no spinner")
+
+	addFollowFlags(rootCmd)
+	addFollowFlags(followCmd)
+	addFollowFlags(watchNamespaceCmd)
+
+	// Dump mode moved to its own command
+	rootCmd.Flags().StringVar(&flags.Dump, "dump", "", "Write the logs to <dir> instead of streaming them")
+	rootCmd.Flags().BoolVar(&flags.WithManifest, "with-manifest", false, "With --dump, also save the pod YAML next to its logs")
+	_ = rootCmd.Flags().MarkDeprecated("dump", "use 'klog dump <pod-name> <dir>'")
+	_ = rootCmd.Flags().MarkDeprecated("with-manifest", "use 'klog dump <pod-name> <dir> --with-manifest'")
+}
 
-	if len(matchedPods) == 0 {
-		pterm.Error.Printf("No pod found with name: %s\n", pod)
-		os.Exit(1)
-	}
+// Function to set the flags of the follow mode, on the root command and on follow
+func addFollowFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&flags.Keyword, "keyword", "k", "", "Keyword for highlighting")
+	cmd.Flags().BoolVar(&flags.KeywordRegex, "keyword-regex", false, "Match the keyword as a regex, e.g. 'timeout|refused', instead of literally")
+	cmd.Flags().BoolVarP(&flags.IgnoreCase, "ignore-case", "i", false, "Highlight the keyword whatever its case, e.g. -k timeout also matching Timeout and TIMEOUT")
+	cmd.Flags().StringArrayVarP(&flags.Exclude, "exclude", "E", nil, "Drop the lines matching this regex, e.g. 'GET /healthz' (repeatable)")
+	cmd.Flags().BoolVar(&flags.NewErrors, "new-errors", false, "Flag with NEW the errors whose message template was not seen before in the session")
+	cmd.Flags().BoolVar(&flags.CrossPod, "cross-pod", false, "With -a, flag errors seen simultaneously in several pods")
+	cmd.Flags().StringVar(&flags.Trigger, "trigger", "", "Save surrounding lines and pod status when a line matches this regex")
+	cmd.Flags().IntVar(&flags.Capture, "capture", 200, "Number of lines saved before and after a --trigger match")
+	cmd.Flags().DurationVar(&flags.ExitIdle, "exit-idle", 0, "Close the session when no line is received for this duration (e.g. 10m)")
+	cmd.Flags().DurationVar(&flags.MaxSession, "max-session", 0, "Close the session after this duration, for credentials or streams that must be cycled (e.g. 8h)")
+	cmd.Flags().BoolVar(&flags.AllContainers, "all-containers", false, "Stream every container of the selected pods at once, lines prefixed with the container name")
+	cmd.Flags().BoolVar(&flags.CollapseProbes, "collapse-probes", false, "Replace the health check requests (kube-probe, /healthz, /readyz) with a summary line per minute")
+	cmd.Flags().StringVar(&flags.TrackField, "track-field", "", "Numeric field of JSON or logfmt lines to watch, e.g. duration_ms, flagged when over --warn-over")
+	cmd.Flags().Float64Var(&flags.WarnOver, "warn-over", 0, "Alert when the average or p95 of --track-field over --track-window crosses this value")
+	cmd.Flags().DurationVar(&flags.TrackWindow, "track-window", time.Minute, "Rolling window of the --track-field statistics")
+	cmd.Flags().DurationVar(&flags.Timeline, "timeline", 0, "Keep a strip per pod of its activity and errors over this duration (e.g. 10m) below the stream")
+	cmd.Flags().BoolVar(&flags.PairRequests, "pair-requests", false, "With --all-containers, display together the lines of the application and its proxy sharing a request ID, held back 2s")
+	cmd.Flags().BoolVar(&flags.Sort, "sort", false, "With -a or --all-containers, merge the streams by timestamp, lines are held back 2s")
+	cmd.Flags().BoolVar(&flags.CorrectSkew, "correct-skew", false, "With -a and -t or --hide-timestamps, shift the timestamps of pods whose clock is skewed to the local clock")
+	cmd.Flags().StringVar(&flags.PrefixTemplate, "prefix-template", "", "Prefix lines with this template of {namespace}, {pod}, {container}, {node}, {workload}, {label:<key>} and {annotation:<key>}")
+	cmd.Flags().StringVar(&flags.ColorBy, "color-by", "pod", "With -a, key pod colors on the 'pod' name or on the 'workload' owning it (remembered across runs)")
+	cmd.Flags().StringVar(&flags.OnlyPods, "only-pods", "", "Only display lines of pods matching this regex")
+	cmd.Flags().StringVar(&flags.OnlyContainers, "only-containers", "", "Only display lines of containers matching this regex")
+	cmd.Flags().StringVar(&flags.OnlyNodes, "only-nodes", "", "Only display lines of pods running on nodes matching this regex")
+	cmd.Flags().StringVar(&flags.Source, "source", "kube", "Log source: 'kube' (pod logs) or 'loki' (LogQL --query)")
+	cmd.Flags().StringVar(&flags.Query, "query", "", "LogQL query streamed with --source loki")
+	cmd.Flags().StringVar(&flags.LokiURL, "loki-url", os.Getenv("LOKI_ADDR"), "Loki address, defaults to $LOKI_ADDR, also used to backfill pod logs rotated away by the kubelet")
+	cmd.Flags().StringVar(&flags.ExportSanitized, "export-sanitized", "", "Write the logs to <file> in time order, redacted, without internal hosts, IPs or debug lines, instead of streaming")
+	cmd.Flags().StringVar(&flags.Level, "min-level", "", "Only display lines of this level and above: debug, info, warn or error (warn+ reads the same)")
+	cmd.Flags().BoolVar(&flags.Prescreen, "prescreen", false, "With -a, show the phases, restarts and images of the matched pods and pick the ones to stream")
+	cmd.Flags().BoolVar(&flags.Summary, "summary", false, "Print the resolved context, namespaces, streams, window, filters and sinks, and confirm them unless --yes is set")
+	cmd.Flags().BoolVar(&flags.Legend, "legend", false, "Print what the pod and level colors, the highlighted keyword and the active filters mean (again with the 'l' command)")
+	cmd.Flags().BoolVar(&flags.CaptureTermination, "capture-termination", false, "When a followed pod is Terminating, mark and save its shutdown lines with the exit code")
+	cmd.Flags().BoolVar(&flags.Rollouts, "rollouts", false, "Insert a separator in the stream when the Deployment of the pods rolls out")
+	cmd.Flags().StringArrayVar(&flags.Dashboards, "dashboard", nil, "Dashboard URL template name=url with {namespace} {pod} {container} {time} {from} {to}, printed by the 'o' command")
+	cmd.Flags().BoolVar(&flags.PrintKubectl, "print-kubectl", false, "Print the equivalent kubectl logs commands, one per stream, instead of streaming")
+	cmd.Flags().StringVarP(&flags.LineOutput, "output", "o", "text", "Line format: 'text' or 'json' (one record per line with namespace, pod, container, time, level and message)")
+	cmd.Flags().BoolVar(&flags.WithPeers, "with-peers", false, "Offer to also stream the pods the selected pod calls and is called by, found through services and peer rules")
+	cmd.Flags().StringSliceVar(&flags.Fields, "fields", nil, "Only display these fields of JSON lines, e.g. msg,level,trace_id (dotted paths for nested fields)")
+	cmd.Flags().IntVar(&flags.SnippetContext, "snippet-context", 5, "Lines of the stream copied before and after the selected line by the s runtime command")
+	cmd.Flags().BoolVar(&flags.MetadataOnly, "metadata-only", false, "Display the time, pod, container, level and length of the lines but not their content, for shared screens")
+	cmd.Flags().BoolVar(&flags.PrettyJSON, "pretty-json", false, "Re-indent single-line JSON logs over several lines with colored keys, strings and numbers")
+	cmd.Flags().StringVar(&flags.CodeURL, "code-url", "", "Link source references like user.go:123 or Java frames to this URL template with {path}, {line} and {revision}")
+	cmd.Flags().BoolVar(&flags.Revision, "revision", false, "Print the git revision of the streamed images (OCI revision label or pod annotation) and in --rollouts separators")
+	cmd.Flags().StringVar(&flags.EmitClassified, "emit-classified", "", "Write every received line to this CSV file with its time, pod, container, level and message template hash")
+	cmd.Flags().StringVar(&flags.OutputFile, "output-file", "", "Also write the displayed lines to <file>, with their colors for 'less -R'")
+	cmd.Flags().StringVar(&flags.OutputDir, "output-dir", "", "Also write the lines of each stream to <dir>/<namespace>_<pod>_<container>.log")
+	cmd.Flags().BoolVar(&flags.OutputPlain, "output-plain", false, "With --output-file or --output-dir, write the lines without colors")
+	cmd.Flags().IntVar(&flags.OutputMaxSize, "output-max-size", 0, "Rotate the output files to <file>.1, <file>.2, ... when they reach N MiB of uncompressed lines")
+	cmd.Flags().IntVar(&flags.OutputMaxFiles, "output-max-files", 5, "Number of rotated output files kept with --output-max-size")
+	cmd.Flags().BoolVar(&flags.OutputCompress, "output-compress", false, "Write the output files gzip-compressed, with a .gz extension")
+	cmd.Flags().BoolVar(&flags.OutputOnly, "output-only", false, "With --output-file or --output-dir, write the lines without displaying them")
+	cmd.Flags().StringVar(&flags.WithExec, "with-exec", "", "Run this command in the followed containers every --exec-interval and show its output in the stream, e.g. 'jstack 1'")
+	cmd.Flags().DurationVar(&flags.ExecInterval, "exec-interval", 5*time.Minute, "Interval of the --with-exec command")
+	cmd.Flags().BoolVar(&flags.Control, "control", false, "Accept 'klog ctl' commands from another terminal on a local socket")
+	cmd.Flags().StringVar(&flags.Serve, "serve", "", "Expose parsed log lines as NDJSON/SSE on <addr>/stream")
+}
 
-	for _, p := range matchedPods {
-		if p.Name == pod {
-			selectedPodName = pod
-			break
-		}
+func main() {
+	if err := rootCmd.Execute(); err != nil {
+		pterm.Error.Print(err)
 	}
+}
 
-	spinner.Success("Initialization success")
+// Function to stream the logs of the selected pod, or of every matched pod with -a, or of every container with --all-containers
+func follow(pattern string, opts Options) {
+	ctx := context.Background()
+	clientset := newClientset(opts)
+	pods := findPods(ctx, clientset, pattern, opts)
 
-	if selectedPodName == "" {
-		podName = selectPod(matchedPods)
+	if opts.AllPods {
+		streamAllPods(ctx, clientset, prescreenPods(pods, opts), pattern, opts)
+		return
 	}
 
-	for _, p := range matchedPods {
-		if p.Name == podName {
-			namespace = p.Namespace
-			break
-		}
+	podInfo := choosePod(ctx, clientset, pods, pattern, opts)
+	// The pod and its peers are streamed together, as with -a, new pods of the pattern left out
+	if peers := choosePeers(ctx, clientset, *podInfo, opts); len(peers) > 0 {
+		opts.AllPods = true
+		streamAllPods(ctx, clientset, append([]v1.Pod{*podInfo}, peers...), "^"+regexp.QuoteMeta(podInfo.Name)+"$", opts)
+		return
 	}
-
-	podInfo, err := clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
-	if err != nil {
-		pterm.Error.Printf("Error fetching pod information: %v\n", err)
-		os.Exit(1)
+	var container string
+	if !opts.AllContainers {
+		container = chooseContainer(podInfo, opts)
 	}
 
-	if container == "" {
-		container = selectContainer(podInfo.Spec.Containers)
+	if opts.PrintKubectl {
+		fmt.Println(kubectlCommand(podInfo.Namespace, podInfo.Name, container, opts))
+		return
 	}
 
-	pterm.Info.Printf("Displaying logs for container '%s' in pod '%s'\n", container, podName)
-
-	// Construct PodLogOptions
-	podLogOptions := &v1.PodLogOptions{
-		Container:  container,
-		Timestamps: timestampFlag, // Display timestamps
-		Follow:     true,          // Enable log streaming by default
-		Previous:   lastContainer, // Display logs of the previous container
+	if opts.AllContainers {
+		streamAllPods(ctx, clientset, []v1.Pod{*podInfo}, pattern, opts)
+		return
 	}
 
-	if sinceTimeFlag > 0 {
-		sinceTime := metav1.NewTime(time.Now().Add(-time.Duration(sinceTimeFlag) * time.Hour))
-		podLogOptions.SinceTime = &sinceTime
-	}
+	checkFetchBudget(ctx, clientset, []v1.Pod{*podInfo}, []string{container}, false, opts)
 
-	if tailLinesFlag > 0 {
-		tailLines := int64(tailLinesFlag)
-		podLogOptions.TailLines = &tailLines
+	if !opts.LastContainer {
+		var err error
+		podInfo, err = waitForContainer(ctx, clientset, podInfo, container, func(text string) progress { return startProgress(text, opts) })
+		if err != nil {
+			pterm.Error.Printf("Error waiting for container: %v\n", err)
+			os.Exit(1)
+		}
 	}
+	podLogOptions := buildLogOptions(podInfo, container, opts)
 
-	// Enable log streaming
-	stream, err := clientset.CoreV1().Pods(namespace).GetLogs(podName, podLogOptions).Stream(ctx)
-	if err != nil {
-		pterm.Error.Printf("Error starting log streaming: %v\n", err)
-		os.Exit(1)
-	}
-	defer stream.Close()
+	pterm.Info.Printf("Displaying logs for container '%s' in pod '%s'\n", container, podInfo.Name)
 
 	// Copy stream to standard output, highlighting log lines
-	scanner := bufio.NewScanner(stream)
-	for scanner.Scan() {
-		// Use function to highlight keyword
-		printLogLine(scanner.Text(), keyword)
-	}
-
-	if err := scanner.Err(); err != nil {
-		pterm.Error.Printf("Error reading logs: %v\n", err)
+	src := logSource{Namespace: podInfo.Namespace, Pod: podInfo.Name, Container: container, Workload: workloadName(*podInfo), Node: podInfo.Spec.NodeName}
+	rememberPodMetadata(*podInfo)
+	startSession(ctx, clientset, []logSource{src}, opts)
+	if err := streamLogs(ctx, clientset, src, podLogOptions, opts); err != nil {
+		pterm.Error.Printf("Error streaming logs: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func loadKubeConfig() *rest.Config {
-	home := homedir.HomeDir()
-	configPath := filepath.Join(home, ".kube", "config")
-
-	config, err := clientcmd.BuildConfigFromFlags("", configPath)
-	if err != nil {
-		pterm.Error.Printf("Error loading Kubernetes configuration: %v\n", err)
-		os.Exit(2)
+// Function to start the session features shared by every stream
+func startSession(ctx context.Context, clientset *kubernetes.Clientset, sources []logSource, opts Options) {
+	sessionSources = sources
+	if opts.Summary {
+		printSummary(sources, opts)
+	}
+	if opts.Legend {
+		printLegend(opts)
 	}
-	return config
+	startCapture(ctx, clientset, sources, opts)
+	session.start = time.Now()
+	startInterruptHandler()
+	startIdleTimer(opts.ExitIdle)
+	startProbeSummaries(opts)
+	startRouting()
+	startOutput(opts)
+	startClassified(opts)
+	startTimeline(opts)
+	startFieldTracking(opts)
+	startControl(opts)
+	startSorting(opts)
+	startPairing(opts)
+	startSessionTimer(opts.MaxSession)
+	startCommands(opts)
+	resolveRevisions(ctx, clientset, sources, opts)
+	go watchRollouts(ctx, clientset, sources, opts)
+	startExecs(ctx, clientset, sources, opts)
 }